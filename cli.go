@@ -0,0 +1,249 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"forger-companion/internal/config"
+	"forger-companion/internal/events"
+	"forger-companion/internal/msgtemplate"
+	"forger-companion/internal/service"
+	"log"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"syscall"
+)
+
+// setFlags collects repeated -set key=value flags.
+type setFlags map[string]string
+
+func (s setFlags) String() string {
+	pairs := make([]string, 0, len(s))
+	for k, v := range s {
+		pairs = append(pairs, k+"="+v)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+func (s setFlags) Set(value string) error {
+	key, val, ok := strings.Cut(value, "=")
+	if !ok || key == "" {
+		return fmt.Errorf("want key=value, got %q", value)
+	}
+	s[key] = val
+	return nil
+}
+
+// runConfigCommand handles "forger-companion config <action>".
+func runConfigCommand(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: config export|explain")
+	}
+
+	switch args[0] {
+	case "export":
+		cfg, err := config.Load()
+		if err != nil {
+			return err
+		}
+		out := json.NewEncoder(os.Stdout)
+		out.SetIndent("", "  ")
+		return out.Encode(cfg.Redacted())
+
+	case "explain":
+		cfg, err := config.Load()
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Profile: %s\n\n", displayProfile(cfg.Profile()))
+		for _, setting := range cfg.Explain() {
+			value, _ := json.Marshal(setting.Value)
+			fmt.Printf("%-36s %-24s %s\n", setting.Path, value, setting.Source)
+		}
+		return nil
+	}
+
+	return fmt.Errorf("unknown config action %q (want export or explain)", args[0])
+}
+
+// runProfileCommand handles "forger-companion profile <action> ...".
+func runProfileCommand(args []string) error {
+	if len(args) == 0 {
+		args = []string{"list"}
+	}
+
+	switch args[0] {
+	case "list":
+		names, err := config.Profiles()
+		if err != nil {
+			return err
+		}
+		active := config.ActiveProfile()
+		if active == "" {
+			fmt.Println("* (base settings)")
+		} else {
+			fmt.Println("  (base settings)")
+		}
+		for _, name := range names {
+			marker := " "
+			if name == active {
+				marker = "*"
+			}
+			fmt.Printf("%s %s\n", marker, name)
+		}
+		return nil
+
+	case "create":
+		if len(args) != 2 {
+			return fmt.Errorf("usage: profile create <name>")
+		}
+		return config.CreateProfile(args[1])
+
+	case "clone":
+		if len(args) != 3 {
+			return fmt.Errorf("usage: profile clone <source> <name>")
+		}
+		return config.CloneProfile(args[1], args[2])
+
+	case "delete":
+		if len(args) != 2 {
+			return fmt.Errorf("usage: profile delete <name>")
+		}
+		return config.DeleteProfile(args[1])
+
+	case "use":
+		name := ""
+		if len(args) == 2 {
+			name = args[1]
+		}
+		if _, err := config.SwitchProfile(name); err != nil {
+			return err
+		}
+		fmt.Printf("Active profile: %s\n", displayProfile(name))
+		return nil
+	}
+
+	return fmt.Errorf("unknown profile action %q (want list, create, clone, delete or use)", args[0])
+}
+
+// runTemplateCommand handles "forger-companion template preview ...".
+func runTemplateCommand(args []string) error {
+	const usage = "usage: template preview [-file template.json] <event> [notifier]"
+	if len(args) == 0 || args[0] != "preview" {
+		return fmt.Errorf(usage)
+	}
+
+	fs := flag.NewFlagSet("template preview", flag.ContinueOnError)
+	file := fs.String("file", "", "render this template file instead of the configured one")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+	if fs.NArg() < 1 || fs.NArg() > 2 {
+		return fmt.Errorf(usage)
+	}
+	event, notifier := fs.Arg(0), fs.Arg(1)
+
+	var tmpl msgtemplate.Template
+	if *file != "" {
+		raw, err := os.ReadFile(*file)
+		if err != nil {
+			return err
+		}
+		if err := json.Unmarshal(raw, &tmpl); err != nil {
+			return fmt.Errorf("%s: %w", *file, err)
+		}
+	} else {
+		cfg, err := config.Load()
+		if err != nil {
+			return err
+		}
+		t, ok := cfg.Webhook.Template(notifier, event)
+		if !ok {
+			return fmt.Errorf("no template for %q in %s", event, cfg.Path())
+		}
+		tmpl = t
+	}
+
+	if err := tmpl.Validate(event); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n\n", err)
+	}
+	sample := msgtemplate.Sample(event)
+	if notifier != "" {
+		sample.Notifier = notifier
+	}
+	r, err := tmpl.Render(sample)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Title:       %s\n", r.Title)
+	fmt.Printf("Description: %s\n", r.Description)
+	fmt.Printf("Color:       #%06x\n", r.Color)
+	fmt.Printf("Footer:      %s\n", r.Footer)
+	for _, f := range r.Fields {
+		fmt.Printf("\n[%s]\n%s\n", f.Name, f.Value)
+	}
+	return nil
+}
+
+// runScanCommand handles "forger-companion scan": one scan of the ores
+// panel, printed as JSON.
+func runScanCommand(svc *service.Service) error {
+	result, err := svc.Scan.Scan()
+	if err != nil {
+		return err
+	}
+	out := json.NewEncoder(os.Stdout)
+	out.SetIndent("", "  ")
+	return out.Encode(result)
+}
+
+// runHeadless handles "forger-companion run [-macro]": no window, events
+// are logged, and Ctrl+C quits. The web UI runs too when enabled.
+func runHeadless(svc *service.Service, args []string) error {
+	flags := flag.NewFlagSet("run", flag.ContinueOnError)
+	startMacro := flags.Bool("macro", false, "start the macro right away")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	svc.Bus.Subscribe(func(e events.Event) {
+		if e.Kind == events.CycleFinished || e.Kind == events.StatsChanged {
+			return
+		}
+		if e.Error != "" {
+			log.Printf("[%s] %s: %s", e.Kind, e.Message, e.Error)
+			return
+		}
+		log.Printf("[%s] %s", e.Kind, e.Message)
+	})
+
+	if *startMacro {
+		if err := svc.Macro.Start(); err != nil {
+			return err
+		}
+	}
+
+	log.Println("Running without a window, press Ctrl+C to quit")
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt, syscall.SIGTERM)
+	<-interrupt
+	return nil
+}
+
+func displayProfile(name string) string {
+	if name == "" {
+		return "(base settings)"
+	}
+	return name
+}
+
+func exitOnError(err error) {
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		os.Exit(1)
+	}
+}
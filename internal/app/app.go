@@ -4,220 +4,268 @@ import (
 	"fmt"
 	"forger-companion/internal/calculator"
 	"forger-companion/internal/config"
-	"forger-companion/internal/macro"
-	"forger-companion/internal/ocr"
-	"log"
-	"time"
+	"forger-companion/internal/events"
+	"forger-companion/internal/service"
+	"forger-companion/internal/stats"
+	"forger-companion/internal/webhook"
 
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/app"
 	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/data/binding"
 	"fyne.io/fyne/v2/widget"
 )
 
 type App struct {
-	cfg     *config.Config
-	scanner *ocr.Scanner
-	macro   *macro.Macro
-	window  fyne.Window
-	
-	// UI elements
-	multiplierLabel *widget.Label
-	oresLabel       *widget.Label
-	statusLabel     *widget.Label
-	scanButton      *widget.Button
-	macroButton     *widget.Button
-	
-	// State
-	scanning bool
-	stopChan chan bool
+	svc    *service.Service
+	window fyne.Window
+	main   fyne.CanvasObject // the tabs, swapped out while setup runs
+
+	// Widgets are bound to these rather than set directly, so the scan
+	// loop, the macro and the config watcher can update them from their
+	// own goroutines.
+	status     binding.String
+	multiplier binding.String
+	ores       binding.String
+	scanText   binding.String
+	macroText  binding.String
+	profiles   binding.StringList
+	profile    binding.String
+	report     binding.Untyped // *stats.Report to show
+
+	profileSelect *widget.Select
+}
+
+func New(svc *service.Service) *App {
+	a := &App{
+		svc:        svc,
+		status:     binding.NewString(),
+		multiplier: binding.NewString(),
+		ores:       binding.NewString(),
+		scanText:   binding.NewString(),
+		macroText:  binding.NewString(),
+		profiles:   binding.NewStringList(),
+		profile:    binding.NewString(),
+		report:     binding.NewUntyped(),
+	}
+	a.status.Set("Ready")
+	a.multiplier.Set("Multiplier: 1.00x")
+	a.ores.Set("No ores detected")
+	a.scanText.Set("Start Scan")
+	a.macroText.Set("Start Macro")
+	a.refreshProfiles()
+
+	svc.Config.Subscribe(func(*config.Config) {
+		a.refreshProfiles()
+	})
+	svc.Bus.Subscribe(a.macroEvent)
+	svc.Bus.Subscribe(a.scanEvent)
+	return a
+}
+
+// settings returns the settings in effect. Read them when needed rather
+// than keeping them, as every change replaces them.
+func (a *App) settings() *config.Config {
+	return a.svc.Config.Current()
 }
 
-func New(cfg *config.Config) *App {
-	scanner := ocr.NewScanner()
-	return &App{
-		cfg:      cfg,
-		scanner:  scanner,
-		macro:    macro.New(cfg, scanner),
-		stopChan: make(chan bool),
+// macroEvent keeps the controls in step with the macro, whether it was
+// started here or remotely, and shows the session report when it ends.
+func (a *App) macroEvent(e events.Event) {
+	switch e.Kind {
+	case events.MacroStarted:
+		a.macroText.Set("Stop Macro")
+		a.status.Set("Macro running...")
+	case events.CycleStarted, events.CycleFinished:
+		a.status.Set(fmt.Sprintf("Macro running... cycle %d", e.Cycle) + webhookStatusText(a.svc.Macro.WebhookStatus()))
+	case events.MacroStopped, events.MacroCrashed:
+		a.macroText.Set("Start Macro")
+		a.status.Set(e.Message)
+		if report := a.svc.Macro.LastReport(); report != nil {
+			a.report.Set(report)
+		}
+	}
+}
+
+// scanEvent shows scan results, including scans requested over the web
+// API.
+func (a *App) scanEvent(e events.Event) {
+	switch e.Kind {
+	case events.ForgeUI:
+		if !e.ForgeOpen || !e.HasOres {
+			a.multiplier.Set("Multiplier: 1.00x")
+			a.ores.Set("Forge UI not detected or no ores placed")
+		}
+	case events.ScanResult:
+		result, ok := e.Data.(*calculator.Result)
+		if !ok {
+			return
+		}
+		if result.OreCount == 0 {
+			a.ores.Set("No ores detected")
+			return
+		}
+
+		a.multiplier.Set(fmt.Sprintf("Multiplier: %.2fx", result.TotalMultiplier))
+
+		oresText := fmt.Sprintf("Detected %d ores:\n", len(result.Ores))
+		for _, ore := range result.Ores {
+			oresText += fmt.Sprintf("• %s x%d (%.1fx)\n", ore.Name, ore.Count, ore.Multiplier)
+		}
+		if result.SellValue > 0 {
+			oresText += fmt.Sprintf("Sell value: $%d", result.SellValue)
+		}
+		a.ores.Set(oresText)
+
+		a.status.Set(fmt.Sprintf("Last scan: %s", e.Time.Format("15:04:05")))
 	}
 }
 
 func (a *App) Run() {
 	fyneApp := app.New()
 	a.window = fyneApp.NewWindow("Forger Companion")
-	
+
 	a.buildUI()
-	
+	if !a.settings().SetupComplete {
+		a.showSetup()
+	}
+	a.report.AddListener(binding.NewDataListener(func() {
+		report, _ := a.report.Get()
+		r, _ := report.(*stats.Report)
+		showReport(a.window, r)
+	}))
+
 	// Set window properties
-	a.window.Resize(fyne.NewSize(400, 300))
-	
+	a.window.Resize(fyne.NewSize(500, 400))
+
 	a.window.ShowAndRun()
-	
-	// Cleanup
-	a.scanner.Close()
 }
 
 func (a *App) buildUI() {
 	// Title
-	title := widget.NewLabelWithStyle("Forger Companion", fyne.TextAlignCenter, fyne.TextStyle{Bold: true})
-	
+	title := widget.NewLabelWithStyle("🔨 Forger Companion", fyne.TextAlignCenter, fyne.TextStyle{Bold: true})
+
 	// Multiplier display
-	a.multiplierLabel = widget.NewLabelWithStyle("Multiplier: 1.00x", fyne.TextAlignCenter, fyne.TextStyle{Bold: true})
-	
+	multiplierLabel := widget.NewLabelWithData(a.multiplier)
+	multiplierLabel.Alignment = fyne.TextAlignCenter
+	multiplierLabel.TextStyle = fyne.TextStyle{Bold: true}
+
 	// Ores display
-	a.oresLabel = widget.NewLabel("No ores detected")
-	a.oresLabel.Wrapping = fyne.TextWrapWord
-	
+	oresLabel := widget.NewLabelWithData(a.ores)
+	oresLabel.Wrapping = fyne.TextWrapWord
+
 	// Status
-	a.statusLabel = widget.NewLabel("Ready")
-	
+	statusLabel := widget.NewLabelWithData(a.status)
+	statusLabel.Wrapping = fyne.TextWrapWord
+
 	// Buttons
 	regionButton := widget.NewButton("Select Region", a.selectRegion)
-	a.scanButton = widget.NewButton("Start Scan", a.toggleScan)
-	a.macroButton = widget.NewButton("Start Macro", a.toggleMacro)
-	
+	scanButton := newBoundButton(a.scanText, a.toggleScan)
+	macroButton := newBoundButton(a.macroText, a.toggleMacro)
+	settingsButton := widget.NewButton("Settings", a.openSettings)
+	setupButton := widget.NewButton("Setup Wizard", a.showSetup)
+
+	// Info
+	infoLabel := widget.NewLabel(
+		"Macro: Hold M1 at break position\n" +
+			"Scan: Detect ores in selected region\n" +
+			"Webhook: Send progress updates",
+	)
+	infoLabel.Wrapping = fyne.TextWrapWord
+
 	// Tabs
 	tabs := container.NewAppTabs(
 		container.NewTabItem("Calculator", container.NewVBox(
-			a.multiplierLabel,
+			multiplierLabel,
 			widget.NewSeparator(),
-			a.oresLabel,
+			oresLabel,
 			widget.NewSeparator(),
 			container.NewGridWithColumns(2,
 				regionButton,
-				a.scanButton,
+				scanButton,
 			),
 		)),
 		container.NewTabItem("Macro", container.NewVBox(
-			widget.NewLabel("Macro Settings"),
-			widget.NewLabel("Configure macro buttons in settings"),
+			infoLabel,
 			widget.NewSeparator(),
-			a.macroButton,
+			macroButton,
+			container.NewGridWithColumns(2, settingsButton, setupButton),
 		)),
+		container.NewTabItem("Remote", a.buildRemoteTab()),
 	)
-	
+
 	// Layout
-	content := container.NewVBox(
+	a.main = container.NewVBox(
 		title,
+		a.buildProfileSelector(),
 		widget.NewSeparator(),
-		a.statusLabel,
+		statusLabel,
 		widget.NewSeparator(),
 		tabs,
 	)
-	
-	a.window.SetContent(content)
-}
 
-func (a *App) selectRegion() {
-	selector := NewRegionSelector(a, func(region *config.Region) {
-		a.cfg.Regions["ores_panel"] = region
-		a.cfg.Save()
-		a.statusLabel.SetText("Region saved!")
-	})
-	selector.Show()
+	a.window.SetContent(a.main)
 }
 
-func (a *App) toggleScan() {
-	if a.scanning {
-		a.stopScan()
-	} else {
-		a.startScan()
-	}
+// newBoundButton makes a button whose label follows text.
+func newBoundButton(text binding.String, tapped func()) *widget.Button {
+	button := widget.NewButton("", tapped)
+	text.AddListener(binding.NewDataListener(func() {
+		label, _ := text.Get()
+		button.SetText(label)
+	}))
+	return button
 }
 
-func (a *App) toggleMacro() {
-	if a.macro.IsRunning() {
-		a.macro.Stop()
-		a.macroButton.SetText("Start Macro")
-		a.statusLabel.SetText("Macro stopped")
-	} else {
-		if err := a.macro.Start(); err != nil {
-			a.statusLabel.SetText(fmt.Sprintf("Macro error: %v", err))
+func (a *App) selectRegion() {
+	selector := NewRegionSelector(a, "ores_panel", func(name string, region *config.Region) {
+		if err := a.svc.Config.SetRegion(name, region); err != nil {
+			a.status.Set(fmt.Sprintf("Error: %v", err))
 			return
 		}
-		a.macroButton.SetText("Stop Macro")
-		a.statusLabel.SetText("Macro running...")
-	}
+		a.status.Set(fmt.Sprintf("Region %s saved!", name))
+	})
+	selector.Show()
 }
 
-func (a *App) startScan() {
-	region := a.cfg.Regions["ores_panel"]
-	if region == nil {
-		a.statusLabel.SetText("Please select a region first")
+func (a *App) toggleScan() {
+	if a.svc.Scan.Running() {
+		a.svc.Scan.Stop()
+		a.scanText.Set("Start Scan")
+		a.status.Set("Stopped")
 		return
 	}
-	
-	a.scanning = true
-	a.scanButton.SetText("Stop Scan")
-	a.statusLabel.SetText("Scanning...")
-	
-	go a.scanLoop(region)
-}
 
-func (a *App) stopScan() {
-	a.scanning = false
-	a.scanButton.SetText("Start Scan")
-	a.statusLabel.SetText("Stopped")
-	a.stopChan <- true
+	if err := a.svc.Scan.Start(); err != nil {
+		a.status.Set("Please select a region first")
+		return
+	}
+	a.scanText.Set("Stop Scan")
+	a.status.Set("Scanning...")
 }
 
-func (a *App) scanLoop(region *config.Region) {
-	interval := 2 * time.Second
-	if scanInterval, ok := a.cfg.Preferences["scan_interval"].(float64); ok {
-		interval = time.Duration(scanInterval * float64(time.Second))
-	}
-	
-	ticker := time.NewTicker(interval)
-	defer ticker.Stop()
-	
-	for a.scanning {
-		select {
-		case <-ticker.C:
-			a.performScan(region)
-		case <-a.stopChan:
-			return
-		}
+// toggleMacro starts or stops the macro; macroEvent updates the controls.
+func (a *App) toggleMacro() {
+	if err := a.svc.Macro.Toggle(); err != nil {
+		a.status.Set(fmt.Sprintf("Macro error: %v", err))
 	}
 }
 
-func (a *App) performScan(region *config.Region) {
-	// Check if forge UI is open
-	isForgeUI, hasOres, err := a.scanner.DetectForgeUI(region)
-	if err != nil {
-		log.Printf("Error detecting forge UI: %v", err)
-		return
+// webhookStatusText summarizes update delivery for the status line.
+func webhookStatusText(status webhook.DeliveryStatus) string {
+	if status.Pending == 0 && status.DeadLettered == 0 {
+		return ""
 	}
-	
-	if !isForgeUI || !hasOres {
-		a.multiplierLabel.SetText("Multiplier: 1.00x")
-		a.oresLabel.SetText("Forge UI not detected or no ores placed")
-		return
-	}
-	
-	// Scan for ores
-	ores, err := a.scanner.ScanForOres(region)
-	if err != nil {
-		log.Printf("Error scanning ores: %v", err)
-		return
+	text := fmt.Sprintf("\nWebhooks: %d pending", status.Pending)
+	if status.DeadLettered > 0 {
+		text += fmt.Sprintf(", %d failed", status.DeadLettered)
 	}
-	
-	if len(ores) == 0 {
-		a.oresLabel.SetText("No ores detected")
-		return
+	if status.LastError != "" {
+		text += "\nLast error: " + status.LastError
 	}
-	
-	// Calculate multiplier
-	result := calculator.Calculate(ores)
-	
-	// Update UI
-	a.multiplierLabel.SetText(fmt.Sprintf("Multiplier: %.2fx", result.TotalMultiplier))
-	
-	oresText := fmt.Sprintf("Detected %d ores:\n", len(ores))
-	for _, ore := range ores {
-		oresText += fmt.Sprintf("• %s x%d (%.1fx)\n", ore.Name, ore.Count, ore.Multiplier)
-	}
-	a.oresLabel.SetText(oresText)
-	
-	a.statusLabel.SetText(fmt.Sprintf("Last scan: %s", time.Now().Format("15:04:05")))
+	return text
+}
+
+func (a *App) openSettings() {
+	a.status.Set(fmt.Sprintf("Settings: Edit %s (changes are applied automatically)", a.settings().Path()))
 }
@@ -0,0 +1,21 @@
+package app
+
+import hook "github.com/robotn/gohook"
+
+// captureKey is pressed in the game to capture the pointer position.
+const captureKey = "f8"
+
+// watchCaptureKey calls fn whenever captureKey is pressed, whichever
+// window has focus: the game does while the pointer is over it, so the
+// app's own key events never see the press. The returned func stops
+// watching.
+func watchCaptureKey(fn func()) (stop func()) {
+	hook.Register(hook.KeyDown, []string{captureKey}, func(hook.Event) {
+		fn()
+	})
+	done := hook.Process(hook.Start())
+	return func() {
+		hook.End()
+		<-done
+	}
+}
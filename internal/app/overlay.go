@@ -0,0 +1,369 @@
+package app
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/driver/desktop"
+	"fyne.io/fyne/v2/widget"
+	"github.com/go-vgo/robotgo"
+)
+
+const (
+	snapDistance   = 8  // snap edges within this many units of a target
+	minSelection   = 5  // smaller drags are treated as clicks
+	magnifierRange = 10 // pixels shown on each side of the cursor
+	magnifierSize  = 126
+)
+
+var (
+	dimColor   = color.NRGBA{A: 110}
+	labelColor = color.NRGBA{R: 255, G: 255, B: 255, A: 255}
+	labelBack  = color.NRGBA{A: 180}
+)
+
+// displayShot is a screenshot of one display.
+type displayShot struct {
+	shot   *image.RGBA     // with bounds starting at 0,0
+	bounds image.Rectangle // the display in screen coordinates
+}
+
+// selectionOverlay shows a frozen, dimmed screenshot of one display and
+// lets the user drag out a rectangle on it, with a size readout and a
+// magnifier at the cursor. Edges snap to the display border and to other
+// regions unless snapping is toggled off.
+//
+// The toolkit can't put a window on a chosen display, so the overlay is
+// given every display and, once the pointer is over it, shows the one it
+// actually covers.
+type selectionOverlay struct {
+	widget.BaseWidget
+
+	displays []displayShot
+	located  bool // the display under the overlay is known
+	shot     *image.RGBA
+	origin   image.Point // the display's top-left in screen coordinates
+	others   []image.Rectangle
+	hint     string
+	snapX    []int // pixel columns edges snap to
+	snapY    []int
+	snap     bool
+	done     func(image.Rectangle) // selection in screen coordinates
+
+	dragging   bool
+	start, end image.Point // selection corners in shot pixels
+	cursor     image.Point
+	hovering   bool
+
+	background *canvas.Image
+	border     *canvas.Rectangle
+	sizeBack   *canvas.Rectangle
+	sizeText   *canvas.Text
+	hintBack   *canvas.Rectangle
+	hintText   *canvas.Text
+	magnifier  *canvas.Image
+	magBorder  *canvas.Rectangle
+	crossH     *canvas.Line
+	crossV     *canvas.Line
+}
+
+// newSelectionOverlay shows displays[first] until it knows which display
+// it covers. others are existing regions in screen coordinates to snap to.
+func newSelectionOverlay(displays []displayShot, first int, hint string, others []image.Rectangle, done func(image.Rectangle)) *selectionOverlay {
+	o := &selectionOverlay{
+		displays: displays,
+		others:   others,
+		hint:     hint,
+		snap:     true,
+		done:     done,
+	}
+
+	o.background = canvas.NewImageFromImage(nil)
+	o.background.FillMode = canvas.ImageFillStretch
+	o.setDisplay(displays[first])
+
+	o.border = drawOverlay()
+	o.border.Hide()
+	o.sizeBack = canvas.NewRectangle(labelBack)
+	o.sizeText = canvas.NewText("", labelColor)
+	o.hintBack = canvas.NewRectangle(labelBack)
+	o.hintText = canvas.NewText("", labelColor)
+	o.hintText.TextStyle = fyne.TextStyle{Bold: true}
+
+	o.magnifier = canvas.NewImageFromImage(image.NewRGBA(image.Rect(0, 0, 2*magnifierRange+1, 2*magnifierRange+1)))
+	o.magnifier.ScaleMode = canvas.ImageScalePixels
+	o.magnifier.FillMode = canvas.ImageFillStretch
+	o.magBorder = canvas.NewRectangle(color.Transparent)
+	o.magBorder.StrokeColor = labelColor
+	o.magBorder.StrokeWidth = 1
+	o.crossH = canvas.NewLine(color.NRGBA{R: 255, A: 200})
+	o.crossV = canvas.NewLine(color.NRGBA{R: 255, A: 200})
+
+	o.ExtendBaseWidget(o)
+	return o
+}
+
+// setDisplay switches the overlay to d.
+func (o *selectionOverlay) setDisplay(d displayShot) {
+	o.shot = d.shot
+	o.origin = d.bounds.Min
+	o.start, o.end = image.Point{}, image.Point{}
+
+	b := d.shot.Bounds()
+	o.snapX = []int{0, b.Dx()}
+	o.snapY = []int{0, b.Dy()}
+	for _, r := range o.others {
+		r = r.Sub(o.origin)
+		o.snapX = append(o.snapX, r.Min.X, r.Max.X)
+		o.snapY = append(o.snapY, r.Min.Y, r.Max.Y)
+	}
+
+	dimmed := image.NewRGBA(b)
+	draw.Draw(dimmed, b, d.shot, b.Min, draw.Src)
+	draw.Draw(dimmed, b, image.NewUniform(dimColor), image.Point{}, draw.Over)
+	o.background.Image = dimmed
+	o.background.Refresh()
+}
+
+// locate switches to the display under the pointer the first time the
+// pointer is over the overlay, which is then the display it covers.
+func (o *selectionOverlay) locate() {
+	if o.located {
+		return
+	}
+	o.located = true
+	x, y := robotgo.Location()
+	for _, d := range o.displays {
+		if image.Pt(x, y).In(d.bounds) {
+			if d.bounds.Min != o.origin {
+				o.setDisplay(d)
+			}
+			return
+		}
+	}
+}
+
+func (o *selectionOverlay) CreateRenderer() fyne.WidgetRenderer {
+	return &overlayRenderer{o: o, objects: []fyne.CanvasObject{
+		o.background, o.border, o.sizeBack, o.sizeText, o.hintBack, o.hintText,
+		o.magnifier, o.magBorder, o.crossH, o.crossV,
+	}}
+}
+
+// ToggleSnap turns edge snapping on or off.
+func (o *selectionOverlay) ToggleSnap() {
+	o.snap = !o.snap
+	o.Refresh()
+}
+
+func (o *selectionOverlay) MouseDown(e *desktop.MouseEvent) {
+	if e.Button != desktop.MouseButtonPrimary {
+		return
+	}
+	o.locate()
+	o.cursor = o.toPixel(e.Position)
+	o.start = o.snapPoint(o.cursor)
+	o.end = o.start
+	o.dragging = true
+	o.Refresh()
+}
+
+func (o *selectionOverlay) MouseUp(e *desktop.MouseEvent) {
+	if !o.dragging {
+		return
+	}
+	o.dragging = false
+	o.end = o.snapPoint(o.toPixel(e.Position))
+	rect := o.selection()
+	if rect.Dx() < minSelection || rect.Dy() < minSelection {
+		o.Refresh()
+		return
+	}
+	o.done(rect.Add(o.origin))
+}
+
+func (o *selectionOverlay) Dragged(e *fyne.DragEvent) {
+	o.cursor = o.toPixel(e.Position)
+	if o.dragging {
+		o.end = o.snapPoint(o.cursor)
+	}
+	o.Refresh()
+}
+
+func (o *selectionOverlay) DragEnd() {}
+
+func (o *selectionOverlay) MouseIn(e *desktop.MouseEvent) {
+	o.hovering = true
+	o.locate()
+	o.MouseMoved(e)
+}
+
+func (o *selectionOverlay) MouseMoved(e *desktop.MouseEvent) {
+	o.cursor = o.toPixel(e.Position)
+	o.Refresh()
+}
+
+func (o *selectionOverlay) MouseOut() {
+	o.hovering = false
+	o.Refresh()
+}
+
+// selection is the dragged rectangle in shot pixels.
+func (o *selectionOverlay) selection() image.Rectangle {
+	return image.Rectangle{Min: o.start, Max: o.end}.Canon()
+}
+
+// scale is shot pixels per canvas unit on each axis.
+func (o *selectionOverlay) scale() (float32, float32) {
+	size := o.Size()
+	if size.Width <= 0 || size.Height <= 0 {
+		return 1, 1
+	}
+	b := o.shot.Bounds()
+	return float32(b.Dx()) / size.Width, float32(b.Dy()) / size.Height
+}
+
+func (o *selectionOverlay) toPixel(pos fyne.Position) image.Point {
+	sx, sy := o.scale()
+	b := o.shot.Bounds()
+	return image.Pt(
+		min(max(int(pos.X*sx+0.5), 0), b.Dx()),
+		min(max(int(pos.Y*sy+0.5), 0), b.Dy()),
+	)
+}
+
+func (o *selectionOverlay) toPosition(p image.Point) fyne.Position {
+	sx, sy := o.scale()
+	return fyne.NewPos(float32(p.X)/sx, float32(p.Y)/sy)
+}
+
+func (o *selectionOverlay) snapPoint(p image.Point) image.Point {
+	if !o.snap {
+		return p
+	}
+	sx, sy := o.scale()
+	p.X = snapValue(p.X, o.snapX, int(snapDistance*sx))
+	p.Y = snapValue(p.Y, o.snapY, int(snapDistance*sy))
+	return p
+}
+
+// snapValue returns the target closest to v if one is within reach.
+func snapValue(v int, targets []int, within int) int {
+	best, bestDist := v, within+1
+	for _, t := range targets {
+		d := t - v
+		if d < 0 {
+			d = -d
+		}
+		if d < bestDist {
+			best, bestDist = t, d
+		}
+	}
+	return best
+}
+
+// magnify copies the pixels around the cursor into the magnifier.
+func (o *selectionOverlay) magnify() {
+	zoom := o.magnifier.Image.(*image.RGBA)
+	draw.Draw(zoom, zoom.Bounds(), image.Black, image.Point{}, draw.Src)
+	src := image.Rect(-magnifierRange, -magnifierRange, magnifierRange+1, magnifierRange+1).Add(o.cursor)
+	draw.Draw(zoom, zoom.Bounds(), o.shot, src.Min, draw.Src)
+}
+
+type overlayRenderer struct {
+	o       *selectionOverlay
+	objects []fyne.CanvasObject
+}
+
+func (r *overlayRenderer) Layout(size fyne.Size) {
+	o := r.o
+	o.background.Resize(size)
+	o.background.Move(fyne.NewPos(0, 0))
+
+	snap := "on"
+	if !o.snap {
+		snap = "off"
+	}
+	o.hintText.Text = fmt.Sprintf("%s   ·   S: snap %s   ·   Esc: cancel", o.hint, snap)
+	hintSize := o.hintText.MinSize()
+	o.hintText.Move(fyne.NewPos((size.Width-hintSize.Width)/2, 12))
+	o.hintBack.Resize(hintSize.Add(fyne.NewSize(16, 8)))
+	o.hintBack.Move(o.hintText.Position().Subtract(fyne.NewPos(8, 4)))
+
+	rect := o.selection()
+	if o.dragging || !rect.Empty() {
+		topLeft := o.toPosition(rect.Min)
+		o.border.Move(topLeft)
+		bottomRight := o.toPosition(rect.Max)
+		o.border.Resize(fyne.NewSize(bottomRight.X-topLeft.X, bottomRight.Y-topLeft.Y))
+		o.border.Show()
+		o.sizeText.Text = fmt.Sprintf("%d × %d at %d, %d", rect.Dx(), rect.Dy(), rect.Min.X+o.origin.X, rect.Min.Y+o.origin.Y)
+	} else {
+		o.border.Hide()
+		o.sizeText.Text = fmt.Sprintf("%d, %d", o.cursor.X+o.origin.X, o.cursor.Y+o.origin.Y)
+	}
+
+	// Readout above the selection, or below the cursor before dragging
+	textSize := o.sizeText.MinSize()
+	textPos := o.toPosition(o.cursor).Add(fyne.NewPos(16, 16))
+	if o.border.Visible() {
+		textPos = o.border.Position().Subtract(fyne.NewPos(0, textSize.Height+6))
+		if textPos.Y < 0 {
+			textPos.Y = o.border.Position().Y + o.border.Size().Height + 6
+		}
+	}
+	o.sizeText.Move(textPos)
+	o.sizeBack.Resize(textSize.Add(fyne.NewSize(8, 4)))
+	o.sizeBack.Move(textPos.Subtract(fyne.NewPos(4, 2)))
+
+	// Magnifier beside the cursor, flipped near the right and bottom edges
+	if !o.hovering && !o.dragging {
+		for _, obj := range []fyne.CanvasObject{o.magnifier, o.magBorder, o.crossH, o.crossV} {
+			obj.Hide()
+		}
+		return
+	}
+	o.magnify()
+	at := o.toPosition(o.cursor)
+	magPos := at.Add(fyne.NewPos(24, 24))
+	if magPos.X+magnifierSize > size.Width {
+		magPos.X = at.X - 24 - magnifierSize
+	}
+	if magPos.Y+magnifierSize > size.Height {
+		magPos.Y = at.Y - 24 - magnifierSize
+	}
+	magSize := fyne.NewSize(magnifierSize, magnifierSize)
+	o.magnifier.Move(magPos)
+	o.magnifier.Resize(magSize)
+	o.magBorder.Move(magPos)
+	o.magBorder.Resize(magSize)
+	center := magPos.Add(fyne.NewPos(magnifierSize/2, magnifierSize/2))
+	o.crossH.Position1 = fyne.NewPos(magPos.X, center.Y)
+	o.crossH.Position2 = fyne.NewPos(magPos.X+magnifierSize, center.Y)
+	o.crossV.Position1 = fyne.NewPos(center.X, magPos.Y)
+	o.crossV.Position2 = fyne.NewPos(center.X, magPos.Y+magnifierSize)
+	for _, obj := range []fyne.CanvasObject{o.magnifier, o.magBorder, o.crossH, o.crossV} {
+		obj.Show()
+	}
+}
+
+func (r *overlayRenderer) MinSize() fyne.Size {
+	return fyne.NewSize(1, 1)
+}
+
+func (r *overlayRenderer) Refresh() {
+	r.Layout(r.o.Size())
+	for _, obj := range r.objects[1:] { // the background never changes
+		obj.Refresh()
+	}
+}
+
+func (r *overlayRenderer) Objects() []fyne.CanvasObject {
+	return r.objects
+}
+
+func (r *overlayRenderer) Destroy() {}
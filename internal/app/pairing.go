@@ -0,0 +1,131 @@
+package app
+
+import (
+	"fmt"
+	"forger-companion/internal/config"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/data/binding"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+	"github.com/skip2/go-qrcode"
+)
+
+// buildRemoteTab shows where the web UI can be reached and manages paired
+// devices.
+func (a *App) buildRemoteTab() fyne.CanvasObject {
+	infoText := binding.NewString()
+	info := widget.NewLabelWithData(infoText)
+	info.Wrapping = fyne.TextWrapWord
+
+	deviceNames := binding.NewStringList()
+	devices := widget.NewListWithData(deviceNames,
+		func() fyne.CanvasObject { return widget.NewLabel("") },
+		func(item binding.DataItem, obj fyne.CanvasObject) {
+			obj.(*widget.Label).Bind(item.(binding.String))
+		},
+	)
+	selected := -1
+	devices.OnSelected = func(id widget.ListItemID) { selected = id }
+	devices.OnUnselected = func(widget.ListItemID) { selected = -1 }
+	deviceNames.AddListener(binding.NewDataListener(devices.UnselectAll))
+
+	refresh := func(web config.WebSettings) {
+		infoText.Set(remoteInfo(web))
+		names := make([]string, len(web.Tokens))
+		for i, t := range web.Tokens {
+			names[i] = fmt.Sprintf("%s (%s)", t.Name, t.Scope)
+		}
+		deviceNames.Set(names)
+	}
+	a.svc.Config.Subscribe(func(cfg *config.Config) { refresh(cfg.Web) })
+	refresh(a.settings().Web)
+
+	pair := widget.NewButton("Pair Device", func() { a.pairDevice() })
+	revoke := widget.NewButton("Revoke", func() {
+		tokens := a.settings().Web.Tokens
+		if selected < 0 || selected >= len(tokens) {
+			return
+		}
+		name := tokens[selected].Name
+		dialog.ShowConfirm("Revoke device", fmt.Sprintf("Stop %s from using the web API?", name), func(ok bool) {
+			if !ok {
+				return
+			}
+			if err := a.svc.Config.RevokeDevice(name); err != nil {
+				dialog.ShowError(err, a.window)
+			}
+		}, a.window)
+	})
+
+	devicesBox := container.NewVScroll(devices)
+	devicesBox.SetMinSize(fyne.NewSize(0, 100))
+	return container.NewVBox(
+		info,
+		widget.NewSeparator(),
+		widget.NewLabel("Paired devices"),
+		devicesBox,
+		container.NewGridWithColumns(2, pair, revoke),
+	)
+}
+
+func remoteInfo(web config.WebSettings) string {
+	var b strings.Builder
+	if web.Enabled {
+		fmt.Fprintf(&b, "Web UI: %s", web.URL())
+	} else {
+		b.WriteString("Web UI is off. Set web.enabled or start with -web 8080.")
+	}
+	if web.Loopback() {
+		b.WriteString("\nOnly reachable from this computer; set web.bind to 0.0.0.0 to use it from your phone.")
+	}
+	return b.String()
+}
+
+// pairDevice creates a token for a new device and shows it as a QR code
+// that opens the web UI already signed in.
+func (a *App) pairDevice() {
+	name := widget.NewEntry()
+	name.SetPlaceHolder("phone")
+	scope := widget.NewSelect(config.WebScopes, nil)
+	scope.SetSelected("control")
+
+	dialog.ShowForm("Pair Device", "Pair", "Cancel", []*widget.FormItem{
+		widget.NewFormItem("Name", name),
+		widget.NewFormItem("Access", scope),
+	}, func(ok bool) {
+		if !ok {
+			return
+		}
+		deviceName := strings.TrimSpace(name.Text)
+		if deviceName == "" {
+			deviceName = "phone"
+		}
+		link, err := a.svc.Config.PairDevice(deviceName, scope.Selected)
+		if err != nil {
+			dialog.ShowError(err, a.window)
+			return
+		}
+		a.showPairingCode(link)
+	}, a.window)
+}
+
+func (a *App) showPairingCode(link string) {
+	qr, err := qrcode.New(link, qrcode.Medium)
+	if err != nil {
+		dialog.ShowError(err, a.window)
+		return
+	}
+	img := canvas.NewImageFromImage(qr.Image(256))
+	img.FillMode = canvas.ImageFillOriginal
+
+	text := widget.NewLabel("Scan with your phone's camera. The code works until the device is revoked.\n" + link)
+	text.Wrapping = fyne.TextWrapBreak
+	if a.settings().Web.TLS {
+		text.SetText(text.Text + "\n\nThe certificate is self-signed, so the browser will ask you to accept it once.")
+	}
+	dialog.ShowCustom("Pair Device", "Done", container.NewVBox(img, text), a.window)
+}
@@ -0,0 +1,82 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"forger-companion/internal/service"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/data/binding"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+)
+
+const baseProfileLabel = "(base settings)"
+
+func (a *App) buildProfileSelector() fyne.CanvasObject {
+	a.profileSelect = widget.NewSelect(nil, func(label string) {
+		name := label
+		if label == baseProfileLabel {
+			name = ""
+		}
+		if name != a.settings().Profile() {
+			a.switchProfile(name)
+		}
+	})
+	a.profiles.AddListener(binding.NewDataListener(func() {
+		options, _ := a.profiles.Get()
+		a.profileSelect.SetOptions(options)
+		a.selectActiveProfile()
+	}))
+	a.profile.AddListener(binding.NewDataListener(a.selectActiveProfile))
+
+	newButton := widget.NewButton("New", func() {
+		dialog.ShowEntryDialog("New Profile", "Name (copies the current settings):", func(name string) {
+			if err := a.svc.Config.ProfileAction("clone", name, a.settings().Profile()); err != nil {
+				a.status.Set(fmt.Sprintf("Error: %v", err))
+				return
+			}
+			a.switchProfile(name)
+		}, a.window)
+	})
+
+	return container.NewBorder(nil, nil, widget.NewLabel("Profile:"), newButton, a.profileSelect)
+}
+
+// refreshProfiles publishes the profile list and the active profile to the
+// selector.
+func (a *App) refreshProfiles() {
+	profiles, err := a.svc.Config.Profiles()
+	if err != nil {
+		a.status.Set(fmt.Sprintf("Error: %v", err))
+	}
+
+	a.profiles.Set(append([]string{baseProfileLabel}, profiles.Profiles...))
+	if profiles.Active == "" {
+		a.profile.Set(baseProfileLabel)
+	} else {
+		a.profile.Set(profiles.Active)
+	}
+}
+
+func (a *App) selectActiveProfile() {
+	label, _ := a.profile.Get()
+	a.profileSelect.SetSelected(label)
+}
+
+// switchProfile loads another profile into the shared config so the macro
+// and webhook manager pick it up without a restart.
+func (a *App) switchProfile(name string) {
+	err := a.svc.Config.SwitchProfile(name)
+	switch {
+	case errors.Is(err, service.ErrMacroRunning):
+		a.status.Set("Stop the macro before switching profiles")
+	case err != nil:
+		a.status.Set(fmt.Sprintf("Error: %v", err))
+	default:
+		a.status.Set(fmt.Sprintf("Switched to profile %s", a.profileSelect.Selected))
+		return
+	}
+	a.refreshProfiles()
+}
@@ -1,124 +1,163 @@
 package app
 
 import (
+	"fmt"
 	"forger-companion/internal/config"
+	"image"
 	"image/color"
+	"sort"
+	"strings"
+	"time"
 
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/canvas"
-	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
 	"fyne.io/fyne/v2/widget"
 	"github.com/go-vgo/robotgo"
+	"github.com/kbinani/screenshot"
 )
 
+// RegionSelector sets a named region by dragging a rectangle on a frozen
+// screenshot of the display it is dragged on.
 type RegionSelector struct {
 	app      *App
-	window   fyne.Window
-	callback func(*config.Region)
-	
-	startX, startY int
-	endX, endY     int
-	selecting      bool
+	name     string
+	callback func(name string, region *config.Region)
 }
 
-func NewRegionSelector(app *App, callback func(*config.Region)) *RegionSelector {
+// NewRegionSelector offers name as the region to set; the user may pick
+// another.
+func NewRegionSelector(app *App, name string, callback func(string, *config.Region)) *RegionSelector {
 	return &RegionSelector{
 		app:      app,
+		name:     name,
 		callback: callback,
 	}
 }
 
+// Show asks which region to set, then opens the overlay.
 func (rs *RegionSelector) Show() {
-	dialog := widget.NewModalPopUp(
-		container.NewVBox(
-			widget.NewLabel("Region Selection"),
-			widget.NewLabel("Instructions:"),
-			widget.NewLabel("1. Click 'Start Selection'"),
-			widget.NewLabel("2. Click and drag to select region"),
-			widget.NewLabel("3. Release to confirm"),
-			widget.NewSeparator(),
-			widget.NewButton("Start Selection", rs.startSelection),
-			widget.NewButton("Cancel", func() {
-				// Close dialog
-			}),
-		),
-		rs.app.window.Canvas(),
-	)
-	
-	dialog.Show()
+	nameEntry := widget.NewSelectEntry(regionNames(rs.app.settings()))
+	nameEntry.SetText(rs.name)
+
+	dialog.ShowForm("Select Region", "Select", "Cancel", []*widget.FormItem{
+		widget.NewFormItem("Region", nameEntry),
+	}, func(ok bool) {
+		name := strings.TrimSpace(nameEntry.Text)
+		if !ok || name == "" {
+			return
+		}
+		rs.Select(name)
+	}, rs.app.window)
 }
 
-func (rs *RegionSelector) startSelection() {
-	// Hide main window temporarily
+// Select hides the app window, screenshots every display and opens the
+// overlay for name. It must be called from a UI callback: the overlay
+// window is created on the calling goroutine.
+func (rs *RegionSelector) Select(name string) {
 	rs.app.window.Hide()
-	
-	// Simple region selection using mouse position
-	// User needs to: click start position, then click end position
-	
-	// Get start position
+	// Give the window time to disappear before taking the screenshots
+	time.Sleep(300 * time.Millisecond)
+
+	displays := make([]displayShot, screenshot.NumActiveDisplays())
+	for i := range displays {
+		shot, err := screenshot.CaptureDisplay(i)
+		if err != nil {
+			rs.app.window.Show()
+			rs.app.status.Set(fmt.Sprintf("Screenshot failed: %v", err))
+			return
+		}
+		displays[i] = displayShot{shot: shot, bounds: screenshot.GetDisplayBounds(i)}
+	}
+	if len(displays) == 0 {
+		rs.app.window.Show()
+		rs.app.status.Set("Screenshot failed: no active display")
+		return
+	}
+	rs.showOverlay(name, displays)
+}
+
+// showOverlay opens the selection window full screen. Fyne can't keep a
+// window above others, but a full-screen window covers the display until
+// it is closed.
+func (rs *RegionSelector) showOverlay(name string, displays []displayShot) {
+	// Start with the display under the pointer, where most window managers
+	// open new windows
+	first := 0
 	x, y := robotgo.Location()
-	rs.startX, rs.startY = x, y
-	
-	// Wait for second click (simplified - in production use proper event handling)
-	// For now, just create a simple dialog
-	rs.app.window.Show()
-	
-	dialog := widget.NewModalPopUp(
-		container.NewVBox(
-			widget.NewLabel("Click to set region corners"),
-			widget.NewLabel("Top-Left: Click anywhere"),
-			widget.NewButton("Set Top-Left", func() {
-				x, y := robotgo.Location()
-				rs.startX, rs.startY = x, y
-			}),
-			widget.NewLabel("Bottom-Right: Click anywhere"),
-			widget.NewButton("Set Bottom-Right", func() {
-				x, y := robotgo.Location()
-				rs.endX, rs.endY = x, y
-			}),
-			widget.NewSeparator(),
-			widget.NewButton("Confirm", func() {
-				rs.confirmSelection()
-			}),
-			widget.NewButton("Cancel", func() {
-				rs.app.window.Show()
-			}),
-		),
-		rs.app.window.Canvas(),
-	)
-	
-	dialog.Show()
+	for i, d := range displays {
+		if image.Pt(x, y).In(d.bounds) {
+			first = i
+		}
+	}
+
+	var others []image.Rectangle
+	for other, r := range rs.app.settings().Regions {
+		if other != name && r != nil {
+			others = append(others, image.Rect(r.X, r.Y, r.X+r.Width, r.Y+r.Height))
+		}
+	}
+
+	window := fyne.CurrentApp().NewWindow("Select " + name)
+	finished := false
+	finish := func(region *config.Region) {
+		if finished {
+			return
+		}
+		finished = true
+		window.Close()
+		rs.app.window.Show()
+		if region != nil && rs.callback != nil {
+			rs.callback(name, region)
+		}
+	}
+
+	hint := fmt.Sprintf("Drag to select %q", name)
+	overlay := newSelectionOverlay(displays, first, hint, others, func(r image.Rectangle) {
+		finish(&config.Region{X: r.Min.X, Y: r.Min.Y, Width: r.Dx(), Height: r.Dy()})
+	})
+	window.Canvas().SetOnTypedKey(func(e *fyne.KeyEvent) {
+		switch e.Name {
+		case fyne.KeyEscape:
+			finish(nil)
+		case fyne.KeyS:
+			overlay.ToggleSnap()
+		}
+	})
+	window.SetOnClosed(func() { finish(nil) })
+	window.SetPadded(false)
+	window.SetContent(overlay)
+	window.SetFullScreen(true)
+	window.Show()
 }
 
-func (rs *RegionSelector) confirmSelection() {
-	width := rs.endX - rs.startX
-	height := rs.endY - rs.startY
-	
-	if width < 0 {
-		rs.startX, rs.endX = rs.endX, rs.startX
-		width = -width
+// regionNames lists the regions the app knows of: the ores panel, the
+// stats panel, those the webhook capture refers to and any already saved.
+func regionNames(cfg *config.Config) []string {
+	seen := map[string]bool{"ores_panel": true, "stats": true}
+	if name := cfg.Webhook.Capture.Region; name != "" {
+		seen[name] = true
 	}
-	if height < 0 {
-		rs.startY, rs.endY = rs.endY, rs.startY
-		height = -height
+	for _, mask := range cfg.Webhook.Capture.Masks {
+		if mask.Region != "" {
+			seen[mask.Region] = true
+		}
 	}
-	
-	region := &config.Region{
-		X:      rs.startX,
-		Y:      rs.startY,
-		Width:  width,
-		Height: height,
+	for name := range cfg.Regions {
+		seen[name] = true
 	}
-	
-	if rs.callback != nil {
-		rs.callback(region)
+
+	names := make([]string, 0, len(seen))
+	for name := range seen {
+		names = append(names, name)
 	}
-	
-	rs.app.window.Show()
+	sort.Strings(names)
+	return names
 }
 
-func (rs *RegionSelector) drawOverlay() *canvas.Rectangle {
-	rect := canvas.NewRectangle(color.RGBA{R: 255, G: 0, B: 0, A: 128})
+// drawOverlay is the outline of the rectangle being selected.
+func drawOverlay() *canvas.Rectangle {
+	rect := canvas.NewRectangle(color.RGBA{R: 255, G: 0, B: 0, A: 40})
 	rect.StrokeColor = color.RGBA{R: 255, G: 0, B: 0, A: 255}
 	rect.StrokeWidth = 2
 	return rect
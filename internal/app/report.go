@@ -0,0 +1,23 @@
+package app
+
+import (
+	"forger-companion/internal/stats"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+)
+
+// showReport pops up the summary of a finished macro session.
+func showReport(window fyne.Window, report *stats.Report) {
+	if window == nil || report == nil {
+		return
+	}
+	text := widget.NewRichTextFromMarkdown(report.Markdown())
+	text.Wrapping = fyne.TextWrapWord
+
+	scroll := container.NewVScroll(text)
+	scroll.SetMinSize(fyne.NewSize(380, 260))
+	dialog.ShowCustom("Session Report", "Close", scroll, window)
+}
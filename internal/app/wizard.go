@@ -0,0 +1,279 @@
+package app
+
+import (
+	"fmt"
+	"forger-companion/internal/config"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/data/binding"
+	"fyne.io/fyne/v2/widget"
+	"github.com/go-vgo/robotgo"
+)
+
+// setupStep is one button or region the wizard asks for.
+type setupStep struct {
+	name   string
+	title  string
+	prompt string
+	region bool
+	key    bool // may be a key instead of a position
+}
+
+var setupSteps = []setupStep{
+	{name: "break_position", title: "Break position",
+		prompt: "In the game, hover over the rock to mine without clicking, then press F8."},
+	{name: "inventory", title: "Inventory", key: true,
+		prompt: "Hover over the inventory button and press F8, or enter the key that opens it."},
+	{name: "sell_tab", title: "Sell tab",
+		prompt: "Open the inventory, hover over the Sell tab and press F8."},
+	{name: "select_all", title: "Select All",
+		prompt: "On the Sell tab, hover over Select All and press F8."},
+	{name: "accept", title: "Accept",
+		prompt: "Hover over Accept and press F8."},
+	{name: "yes_confirm", title: "Confirm",
+		prompt: "Hover over where Yes appears in the sell confirmation and press F8."},
+	{name: "close_menu", title: "Close menu",
+		prompt: "Hover over the button that closes the inventory and press F8."},
+	{name: "ores_panel", title: "Ores panel", region: true,
+		prompt: "Open the forge with some ores placed and select the panel listing them."},
+}
+
+// setupWizard walks through setupSteps in the main window, saving each
+// capture as it's made. Setup is marked complete only once every required
+// button and region is set. Positions are captured with F8 while the game
+// has focus, or after a countdown where the key can't be watched.
+type setupWizard struct {
+	a      *App
+	step   int
+	active atomic.Pointer[setupStep] // step F8 captures for
+	stop   func()                    // stops watching F8
+
+	title   *widget.Label
+	prompt  *widget.Label
+	value   binding.String // what the step is set to
+	result  binding.String // capture and test feedback
+	actions *fyne.Container
+	back    *widget.Button
+	next    *widget.Button
+}
+
+// showSetup replaces the main window's content with the wizard until it
+// is finished or put off.
+func (a *App) showSetup() {
+	w := &setupWizard{
+		a:       a,
+		title:   widget.NewLabelWithStyle("", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+		prompt:  widget.NewLabel(""),
+		value:   binding.NewString(),
+		result:  binding.NewString(),
+		actions: container.NewHBox(),
+	}
+	w.prompt.Wrapping = fyne.TextWrapWord
+	valueLabel := widget.NewLabelWithData(w.value)
+	resultLabel := widget.NewLabelWithData(w.result)
+	resultLabel.Wrapping = fyne.TextWrapWord
+
+	w.back = widget.NewButton("Back", func() { w.show(w.step - 1) })
+	w.next = widget.NewButton("Next", w.advance)
+	later := widget.NewButton("Later", w.close)
+
+	a.window.SetContent(container.NewVBox(
+		widget.NewLabelWithStyle("Setup", fyne.TextAlignCenter, fyne.TextStyle{Bold: true}),
+		widget.NewSeparator(),
+		w.title,
+		w.prompt,
+		valueLabel,
+		w.actions,
+		resultLabel,
+		widget.NewSeparator(),
+		container.NewGridWithColumns(3, w.back, later, w.next),
+	))
+	w.stop = watchCaptureKey(func() {
+		if s := w.active.Load(); s != nil {
+			w.capturePosition(*s)
+		}
+	})
+	w.show(0)
+}
+
+func (w *setupWizard) show(step int) {
+	if step < 0 || step >= len(setupSteps) {
+		return
+	}
+	w.step = step
+	s := setupSteps[step]
+	w.active.Store(&s)
+
+	w.title.SetText(fmt.Sprintf("Step %d of %d: %s", step+1, len(setupSteps), s.title))
+	w.prompt.SetText(s.prompt)
+	w.result.Set("")
+	w.refreshValue(s)
+
+	w.actions.RemoveAll()
+	if s.region {
+		w.actions.Add(widget.NewButton("Select Region", func() { w.selectRegion(s) }))
+		w.actions.Add(widget.NewButton("Test Scan", w.testScan))
+	} else {
+		w.actions.Add(widget.NewButton("Capture in 3s", func() {
+			countdown(w.result, "Hover over the target, capturing in %d...", func() { w.capturePosition(s) })
+		}))
+		if s.key {
+			key := widget.NewEntry()
+			key.SetPlaceHolder("e")
+			if b := w.a.settings().MacroButtons[s.name]; b != nil && b.Key != nil {
+				key.SetText(*b.Key)
+			}
+			w.actions.Add(key)
+			w.actions.Add(widget.NewButton("Use Key", func() { w.setKey(s, key.Text) }))
+		}
+		w.actions.Add(widget.NewButton("Test", func() { w.testButton(s) }))
+	}
+
+	if step == 0 {
+		w.back.Disable()
+	} else {
+		w.back.Enable()
+	}
+	if step == len(setupSteps)-1 {
+		w.next.SetText("Finish")
+	} else {
+		w.next.SetText("Next")
+	}
+}
+
+func (w *setupWizard) advance() {
+	if w.step < len(setupSteps)-1 {
+		w.show(w.step + 1)
+		return
+	}
+	if err := w.a.svc.Config.CompleteSetup(); err != nil {
+		w.result.Set(err.Error())
+		return
+	}
+	w.a.status.Set("Setup complete")
+	w.close()
+}
+
+func (w *setupWizard) close() {
+	w.stop()
+	w.a.window.SetContent(w.a.main)
+}
+
+func (w *setupWizard) refreshValue(s setupStep) {
+	if s.region {
+		w.value.Set("Current: " + describeRegion(w.a.settings().Regions[s.name]))
+		return
+	}
+	w.value.Set("Current: " + describeButton(w.a.settings().MacroButtons[s.name]))
+}
+
+func (w *setupWizard) capturePosition(s setupStep) {
+	if s.region {
+		return
+	}
+	x, y := robotgo.Location()
+	if err := w.a.svc.Config.SetButton(s.name, &config.MacroButton{X: &x, Y: &y}); err != nil {
+		w.result.Set(fmt.Sprintf("Error: %v", err))
+		return
+	}
+	w.refreshValue(s)
+	w.result.Set(fmt.Sprintf("Captured %d, %d. Press Test to check it.", x, y))
+}
+
+func (w *setupWizard) setKey(s setupStep, key string) {
+	key = strings.ToLower(strings.TrimSpace(key))
+	if key == "" {
+		w.result.Set("Enter a key such as e")
+		return
+	}
+	if err := w.a.svc.Config.SetButton(s.name, &config.MacroButton{Key: &key}); err != nil {
+		w.result.Set(fmt.Sprintf("Error: %v", err))
+		return
+	}
+	w.refreshValue(s)
+	w.result.Set(fmt.Sprintf("Using the %q key. Press Test to check it.", key))
+}
+
+// testButton moves the pointer to a captured position, or presses a
+// captured key once the game has had time to come to the front.
+func (w *setupWizard) testButton(s setupStep) {
+	b := w.a.settings().MacroButtons[s.name]
+	switch {
+	case b == nil:
+		w.result.Set("Capture it first")
+	case b.Key != nil:
+		key := *b.Key
+		countdown(w.result, "Switch to the game, pressing "+key+" in %d...", func() {
+			if err := robotgo.KeyTap(key); err != nil {
+				w.result.Set(fmt.Sprintf("Pressing %s failed: %v", key, err))
+				return
+			}
+			w.result.Set(fmt.Sprintf("Pressed %s. Did the inventory open?", key))
+		})
+	case b.HasPosition():
+		robotgo.Move(*b.X, *b.Y)
+		w.result.Set(fmt.Sprintf("Moved the pointer to %d, %d. Is it over the %s?", *b.X, *b.Y, s.title))
+	}
+}
+
+func (w *setupWizard) selectRegion(s setupStep) {
+	NewRegionSelector(w.a, s.name, func(name string, region *config.Region) {
+		if err := w.a.svc.Config.SetRegion(name, region); err != nil {
+			w.result.Set(fmt.Sprintf("Error: %v", err))
+			return
+		}
+		w.refreshValue(s)
+		w.result.Set("Region saved. Press Test Scan to check it.")
+	}).Show()
+}
+
+// testScan runs one forge scan so the user can see the region reads.
+func (w *setupWizard) testScan() {
+	w.result.Set("Scanning...")
+	go func() {
+		result, err := w.a.svc.Scan.Scan()
+		switch {
+		case err != nil:
+			w.result.Set(fmt.Sprintf("Scan failed: %v", err))
+		case result.OreCount == 0:
+			w.result.Set("No ores found in the region. Check it covers the ore list.")
+		default:
+			w.result.Set(fmt.Sprintf("Found %d ores, multiplier %.2fx", result.OreCount, result.TotalMultiplier))
+		}
+	}()
+}
+
+// countdown shows the seconds left in text for three seconds, then runs
+// fn.
+func countdown(text binding.String, format string, fn func()) {
+	go func() {
+		for i := 3; i > 0; i-- {
+			text.Set(fmt.Sprintf(format, i))
+			time.Sleep(time.Second)
+		}
+		fn()
+	}()
+}
+
+func describeButton(b *config.MacroButton) string {
+	switch {
+	case b == nil:
+		return "not set"
+	case b.Key != nil:
+		return fmt.Sprintf("%q key", *b.Key)
+	case b.HasPosition():
+		return fmt.Sprintf("%d, %d", *b.X, *b.Y)
+	}
+	return "not set"
+}
+
+func describeRegion(r *config.Region) string {
+	if r == nil {
+		return "not set"
+	}
+	return fmt.Sprintf("%d × %d at %d, %d", r.Width, r.Height, r.X, r.Y)
+}
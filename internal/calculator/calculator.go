@@ -6,23 +6,27 @@ import (
 )
 
 type Result struct {
-	TotalMultiplier float64
-	OreCount        int
-	Ores            map[string]ocr.DetectedOre
+	TotalMultiplier float64                    `json:"total_multiplier"`
+	OreCount        int                        `json:"ore_count"`
+	SellValue       int                        `json:"sell_value"`
+	Ores            map[string]ocr.DetectedOre `json:"ores"`
 }
 
 func Calculate(ores map[string]ocr.DetectedOre) *Result {
 	totalMultiplier := 1.0
 	totalOres := 0
+	sellValue := 0
 
 	for _, ore := range ores {
 		totalMultiplier *= math.Pow(ore.Multiplier, float64(ore.Count))
 		totalOres += ore.Count
+		sellValue += ore.SellPrice * ore.Count
 	}
 
 	return &Result{
 		TotalMultiplier: totalMultiplier,
 		OreCount:        totalOres,
+		SellValue:       sellValue,
 		Ores:            ores,
 	}
 }
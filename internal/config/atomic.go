@@ -0,0 +1,98 @@
+package config
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// backupCount is how many previous versions of a settings file are kept
+// as <file>.bak.1 (newest) through <file>.bak.N.
+const backupCount = 3
+
+// written remembers the content hash of every file this process wrote so
+// the watcher can tell our own saves from external edits.
+var written = struct {
+	sync.Mutex
+	hashes map[string][32]byte
+}{hashes: make(map[string][32]byte)}
+
+func rememberHash(path string, data []byte) {
+	written.Lock()
+	written.hashes[path] = sha256.Sum256(data)
+	written.Unlock()
+}
+
+// writeFileAtomic replaces path with data via a synced temp file and a
+// rename, so a crash leaves either the old or the new file, never a
+// truncated one. With backups set, the previous content is rotated into
+// numbered .bak files first.
+func writeFileAtomic(path string, data []byte, backups bool) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // no-op once renamed
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return err
+	}
+
+	if backups {
+		if err := rotateBackups(path); err != nil {
+			return fmt.Errorf("rotate backups: %w", err)
+		}
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return err
+	}
+	rememberHash(path, data)
+	syncDir(dir)
+	return nil
+}
+
+func rotateBackups(path string) error {
+	current, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	for i := backupCount - 1; i >= 1; i-- {
+		older := fmt.Sprintf("%s.bak.%d", path, i)
+		if err := os.Rename(older, fmt.Sprintf("%s.bak.%d", path, i+1)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return os.WriteFile(path+".bak.1", current, 0644)
+}
+
+// syncDir flushes the directory entry after a rename. Not every platform
+// supports syncing a directory, so failures are ignored.
+func syncDir(dir string) {
+	if d, err := os.Open(dir); err == nil {
+		d.Sync()
+		d.Close()
+	}
+}
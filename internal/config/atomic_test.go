@@ -0,0 +1,51 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteFileAtomicRotatesBackups(t *testing.T) {
+	tests := []struct {
+		name    string
+		writes  int
+		backups bool
+		want    map[string]string // file suffix to content, "" for missing
+	}{
+		{"first write", 1, true, map[string]string{"": "v1", ".bak.1": ""}},
+		{"second write", 2, true, map[string]string{"": "v2", ".bak.1": "v1", ".bak.2": ""}},
+		{"full rotation", 4, true, map[string]string{"": "v4", ".bak.1": "v3", ".bak.2": "v2", ".bak.3": "v1"}},
+		{"oldest dropped", 6, true, map[string]string{"": "v6", ".bak.1": "v5", ".bak.3": "v3", ".bak.4": ""}},
+		{"no backups", 3, false, map[string]string{"": "v3", ".bak.1": ""}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "settings.json")
+			for i := 1; i <= tt.writes; i++ {
+				if err := writeFileAtomic(path, []byte(fmt.Sprintf("v%d", i)), tt.backups); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			for suffix, want := range tt.want {
+				got, err := os.ReadFile(path + suffix)
+				switch {
+				case want == "" && !os.IsNotExist(err):
+					t.Errorf("settings.json%s exists (err %v)", suffix, err)
+				case want != "" && err != nil:
+					t.Errorf("settings.json%s: %v", suffix, err)
+				case want != "" && string(got) != want:
+					t.Errorf("settings.json%s = %q, want %q", suffix, got, want)
+				}
+			}
+
+			temps, _ := filepath.Glob(filepath.Join(dir, ".*.tmp"))
+			if len(temps) > 0 {
+				t.Errorf("temp files left behind: %v", temps)
+			}
+		})
+	}
+}
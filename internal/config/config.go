@@ -2,8 +2,14 @@ package config
 
 import (
 	"encoding/json"
+	"fmt"
+	"forger-companion/internal/data"
+	"forger-companion/internal/events"
+	"forger-companion/internal/msgtemplate"
+	"log"
 	"os"
 	"path/filepath"
+	"time"
 )
 
 type Region struct {
@@ -20,90 +26,412 @@ type MacroButton struct {
 }
 
 type WebhookSettings struct {
-	Enabled       bool   `json:"enabled"`
-	Mode          string `json:"mode"` // "bot" or "webhook"
-	DiscordID     string `json:"discord_id"`
-	WebhookURL    string `json:"webhook_url"`
-	CycleInterval int    `json:"cycle_interval"`
-	SendGIF       bool   `json:"send_gif"`
-	GIFFrames     int    `json:"gif_frames"`
-	GIFDuration   int    `json:"gif_duration"`
-	TrackStats    bool   `json:"track_stats"`
+	Enabled       bool             `json:"enabled"`
+	Notifiers     []NotifierConfig `json:"notifiers"`
+	CycleInterval int              `json:"cycle_interval"`
+	SendGIF       bool             `json:"send_gif"`
+	GIFFrames     int              `json:"gif_frames"`
+	GIFDuration   int              `json:"gif_duration"`
+	TrackStats    bool             `json:"track_stats"`
+
+	// MinRarity limits which tracked ores are listed in updates.
+	MinRarity data.Rarity `json:"min_rarity,omitempty"`
+	// NotifyMinRarity, when set, only sends an update once an ore of at
+	// least this rarity has been found since the previous one.
+	NotifyMinRarity data.Rarity `json:"notify_min_rarity,omitempty"`
+
+	// Events decides which alerts are sent, keyed by event kind.
+	Events map[string]EventRule `json:"events"`
+	// MoneyMilestone sends a money_milestone alert each time money passes
+	// a multiple of this amount. 0 disables it.
+	MoneyMilestone int `json:"money_milestone"`
+
+	// Templates customize messages, keyed by "progress" or an event kind.
+	Templates map[string]msgtemplate.Template `json:"templates,omitempty"`
+
+	Capture CaptureSettings `json:"capture"`
+}
+
+// CaptureModes lists what a progress image can show.
+var CaptureModes = []string{"fullscreen", "window", "region"}
+
+// CaptureSettings controls the screenshots attached to updates.
+type CaptureSettings struct {
+	// Mode is "fullscreen", "window" (the game window only) or "region"
+	// (the named entry in regions).
+	Mode   string `json:"mode"`
+	Region string `json:"region,omitempty"`
+	// Masks hide parts of the screen such as chat or player names.
+	Masks []Mask `json:"masks,omitempty"`
+
+	Scale       float64 `json:"scale"`        // 0.1-1, applied before encoding
+	Resample    string  `json:"resample"`     // "box" or "bilinear"
+	Format      string  `json:"format"`       // "png" or "jpeg"
+	JPEGQuality int     `json:"jpeg_quality"` // 1-100
+}
+
+// Mask covers an area of the screen, given either as a named region or as
+// screen coordinates.
+type Mask struct {
+	Region string `json:"region,omitempty"`
+	X      int    `json:"x,omitempty"`
+	Y      int    `json:"y,omitempty"`
+	Width  int    `json:"width,omitempty"`
+	Height int    `json:"height,omitempty"`
+	Style  string `json:"style"` // "blur" or "blackout"
+}
+
+// EventRule controls alerts for one event kind.
+type EventRule struct {
+	Enabled bool `json:"enabled"`
+	// Notifiers names the notifiers to alert; empty means all enabled ones.
+	Notifiers []string `json:"notifiers,omitempty"`
+	// Cooldown suppresses repeats of this event for a while.
+	Cooldown Seconds `json:"cooldown"`
+	// MinRarity applies to ore_found alerts.
+	MinRarity data.Rarity `json:"min_rarity,omitempty"`
+}
+
+// Template returns the message template for event, preferring one set on
+// the named notifier over the shared webhook.templates entry.
+func (w WebhookSettings) Template(notifier, event string) (msgtemplate.Template, bool) {
+	for _, nc := range w.Notifiers {
+		if nc.Name == notifier {
+			if t, ok := nc.Templates[event]; ok {
+				return t, true
+			}
+		}
+	}
+	t, ok := w.Templates[event]
+	return t, ok
+}
+
+// WebScopes lists what a web API token may do: "read" sees state and
+// settings, "control" can also start the macro and change settings.
+var WebScopes = []string{"read", "control"}
+
+// WebSettings configures the web API and phone UI.
+type WebSettings struct {
+	Enabled bool `json:"enabled"`
+	// Bind is the address to listen on: "127.0.0.1" for this computer
+	// only, "0.0.0.0" for the LAN. Anything but loopback needs a token.
+	Bind string `json:"bind"`
+	Port int    `json:"port"`
+	// TLS serves HTTPS with a self-signed certificate.
+	TLS    bool       `json:"tls"`
+	Tokens []WebToken `json:"tokens,omitempty"`
+}
+
+// WebToken lets a paired device use the web API.
+type WebToken struct {
+	Name  string `json:"name"`
+	Token Secret `json:"token"`
+	Scope string `json:"scope"` // "read" or "control"
+}
+
+// NotifierTypes lists the notification backends a NotifierConfig can use.
+var NotifierTypes = []string{"discord", "bot", "slack", "telegram", "ntfy", "json"}
+
+// NotifierConfig is one notification target. Several can be enabled at
+// once; which fields are needed depends on Type.
+type NotifierConfig struct {
+	Name    string `json:"name"`
+	Type    string `json:"type"`
+	Enabled bool   `json:"enabled"`
+
+	URL        Secret `json:"url,omitempty"`         // discord, slack, ntfy topic, json endpoint
+	Token      Secret `json:"token,omitempty"`       // telegram bot token, ntfy access token
+	DiscordID  Secret `json:"discord_id,omitempty"`  // bot
+	LicenseKey Secret `json:"license_key,omitempty"` // bot, signs requests
+	ChatID     string `json:"chat_id,omitempty"`     // telegram
+	APIBase    string `json:"api_base,omitempty"`    // telegram and bot, for self-hosted servers
+
+	// Template is a Go text/template rendering the request body of a
+	// "json" notifier. Empty sends the message as plain JSON.
+	Template string            `json:"template,omitempty"`
+	Headers  map[string]string `json:"headers,omitempty"` // json
+
+	// Templates override webhook.templates for this notifier.
+	Templates map[string]msgtemplate.Template `json:"templates,omitempty"`
 }
 
 type Config struct {
-	SetupComplete bool                       `json:"setup_complete"`
-	Regions       map[string]*Region         `json:"regions"`
-	MacroButtons  map[string]*MacroButton    `json:"macro_buttons"`
-	MacroSettings map[string]interface{}     `json:"macro_settings"`
-	Webhook       WebhookSettings            `json:"webhook"`
-	Preferences   map[string]interface{}     `json:"preferences"`
-	Window        map[string]interface{}     `json:"window"`
+	SchemaVersion int                     `json:"schema_version"`
+	SetupComplete bool                    `json:"setup_complete"`
+	Regions       map[string]*Region      `json:"regions"`
+	MacroButtons  map[string]*MacroButton `json:"macro_buttons"`
+	MacroSettings MacroSettings           `json:"macro_settings"`
+	Webhook       WebhookSettings         `json:"webhook"`
+	Preferences   Preferences             `json:"preferences"`
+	Web           WebSettings             `json:"web"`
+	Window        map[string]interface{}  `json:"window"`
+
+	profile   string
+	sources   map[string]Source      // where each effective value came from
+	persisted map[string]interface{} // values before env/flag overrides
+	loadErr   error                  // why the file's own values aren't all here
 }
 
 func Default() *Config {
 	return &Config{
+		SchemaVersion: SchemaVersion,
 		SetupComplete: false,
 		Regions:       make(map[string]*Region),
 		MacroButtons:  make(map[string]*MacroButton),
-		MacroSettings: map[string]interface{}{
-			"enabled":       false,
-			"hold_duration": 5,
-			"auto_sell":     true,
+		MacroSettings: MacroSettings{
+			Enabled:      false,
+			HoldDuration: Minutes(5 * time.Minute),
+			AutoSell:     true,
 		},
 		Webhook: WebhookSettings{
 			Enabled:       false,
-			Mode:          "bot",
+			Notifiers:     []NotifierConfig{},
 			CycleInterval: 5,
 			SendGIF:       false,
 			GIFFrames:     5,
 			GIFDuration:   500,
 			TrackStats:    false,
+			MinRarity:     data.Legendary,
+			Events: map[string]EventRule{
+				string(events.MacroStarted):   {Enabled: false},
+				string(events.MacroStopped):   {Enabled: true},
+				string(events.MacroCrashed):   {Enabled: true},
+				string(events.SellFailed):     {Enabled: true, Cooldown: Seconds(15 * time.Minute)},
+				string(events.GameWindowLost): {Enabled: true, Cooldown: Seconds(15 * time.Minute)},
+				string(events.OreFound):       {Enabled: true, MinRarity: data.Mythical},
+				string(events.LevelUp):        {Enabled: false},
+				string(events.MoneyMilestone): {Enabled: false},
+				string(events.SessionReport):  {Enabled: true},
+			},
+			MoneyMilestone: 1000000,
+			Capture: CaptureSettings{
+				Mode:        "fullscreen",
+				Scale:       0.5,
+				Resample:    "box",
+				Format:      "png",
+				JPEGQuality: 85,
+			},
 		},
-		Preferences: map[string]interface{}{
-			"auto_mode":        true,
-			"always_on_top":    true,
-			"auto_switch_tab":  true,
-			"opacity":          95,
-			"scan_interval":    2.0,
-			"macro_hotkey":     "f6",
+		Preferences: Preferences{
+			AutoMode:      true,
+			AlwaysOnTop:   true,
+			AutoSwitchTab: true,
+			Opacity:       95,
+			ScanInterval:  Seconds(2 * time.Second),
+			MacroHotkey:   "f6",
+		},
+		Web: WebSettings{
+			Bind: "127.0.0.1",
+			Port: 8080,
 		},
 		Window: make(map[string]interface{}),
 	}
 }
 
-func configPath() string {
+// Dir is the directory holding settings and user data files.
+func Dir() string {
+	if pathOverride != "" {
+		return filepath.Dir(pathOverride)
+	}
 	home, _ := os.UserHomeDir()
-	return filepath.Join(home, ".forger-companion", "settings.json")
+	return filepath.Join(home, ".forger-companion")
 }
 
+func configPath() string {
+	if pathOverride != "" {
+		return pathOverride
+	}
+	return filepath.Join(Dir(), "settings.json")
+}
+
+// SessionsDir holds the reports saved when the macro stops.
+func SessionsDir() string {
+	return filepath.Join(Dir(), "sessions")
+}
+
+// OresPath is the optional user file that adds or overrides ore data.
+func OresPath() string {
+	return filepath.Join(Dir(), "ores.json")
+}
+
+// Load reads the settings for the active profile.
 func Load() (*Config, error) {
-	path := configPath()
-	data, err := os.ReadFile(path)
+	return LoadProfile(ActiveProfile())
+}
+
+// LoadProfile layers a profile over the base settings.json, fills in
+// defaults for anything neither file sets and applies environment and flag
+// overrides on top. An empty name loads the base settings alone.
+func LoadProfile(name string) (*Config, error) {
+	paths := []string{configPath()}
+	if name != "" {
+		if err := checkProfileName(name); err != nil {
+			return nil, err
+		}
+		paths = append(paths, profilePath(name))
+	}
+
+	merged := defaultTree()
+	sources := make(map[string]Source)
+	recordSources(merged, "", Source{Layer: LayerDefault}, sources)
+	for i, path := range paths {
+		tree, err := readTree(path)
+		if os.IsNotExist(err) && i == 0 {
+			continue // first run, or a profile standing without base settings
+		}
+		if err != nil {
+			return nil, err
+		}
+		mergeTree(merged, tree)
+
+		layer := LayerFile
+		if i > 0 {
+			layer = LayerProfile
+		}
+		recordSources(tree, "", Source{Layer: layer, Detail: path}, sources)
+	}
+	source := paths[len(paths)-1]
+
+	persisted := copyTree(merged)
+	if err := applyOverrides(merged, sources); err != nil {
+		return nil, err
+	}
+
+	data, err := json.Marshal(merged)
 	if err != nil {
 		return nil, err
 	}
 
-	var cfg Config
-	if err := json.Unmarshal(data, &cfg); err != nil {
+	cfg, unknown, err := Decode(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", source, err)
+	}
+	for _, key := range unknown {
+		log.Printf("[Config] Unknown setting %q in %s", key, source)
+	}
+
+	if err := cfg.Validate(); err != nil {
+		err = fmt.Errorf("invalid settings in %s:\n%w", source, err)
+		// Hand back the rest of the file's values so callers that must
+		// start can, but never let these settings be saved over it
+		repaired, rerr := repair(merged)
+		if rerr != nil {
+			return nil, err
+		}
+		cfg = repaired
+		cfg.loadErr = err
+	}
+
+	cfg.profile = name
+	cfg.sources = sources
+	cfg.persisted = persisted
+	return cfg, cfg.loadErr
+}
+
+// readTree reads a settings file as a generic JSON tree. The file is
+// rewritten first if it was written by an older version or holds plaintext
+// secrets.
+func readTree(path string) (map[string]interface{}, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
 		return nil, err
 	}
 
-	return &cfg, nil
+	var tree map[string]interface{}
+	if err := json.Unmarshal(raw, &tree); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	// Move hand-typed secrets out first so no backup keeps them in plaintext
+	secured := secureTree(tree, secretScope(path), nil)
+	if secured {
+		raw, _ = json.MarshalIndent(tree, "", "  ")
+	}
+
+	migrated, err := migrate(path, raw, tree)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if migrated && secureTree(tree, secretScope(path), nil) {
+		secured = true // a migration may have moved secrets to new fields
+	}
+	if migrated || secured {
+		data, err := json.MarshalIndent(tree, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		if err := writeFileAtomic(path, data, !secured); err != nil {
+			return nil, fmt.Errorf("rewrite settings: %w", err)
+		}
+	}
+
+	return tree, nil
 }
 
+// Profile is the name of the profile these settings were loaded from, or
+// empty for the base settings.
+func (c *Config) Profile() string {
+	return c.profile
+}
+
+// Path is the file Save writes to.
+func (c *Config) Path() string {
+	if c.profile != "" {
+		return profilePath(c.profile)
+	}
+	return configPath()
+}
+
+// Fallback returns default settings to run with when the settings file
+// exists but failed to load with err. They are never saved over it.
+func Fallback(err error) *Config {
+	cfg := Default()
+	cfg.loadErr = err
+	return cfg
+}
+
+// LoadError is why these settings don't hold everything in their file, or
+// nil. Such settings can't be saved until the file loads cleanly.
+func (c *Config) LoadError() error {
+	return c.loadErr
+}
+
+// Save writes the settings back to the profile they came from, with any
+// plaintext secrets moved into the secrets store. Settings that failed to
+// load are not saved, so defaults never replace the user's file.
 func (c *Config) Save() error {
-	path := configPath()
-	dir := filepath.Dir(path)
-	
-	if err := os.MkdirAll(dir, 0755); err != nil {
+	if c.loadErr != nil {
+		return fmt.Errorf("not saving over %s, which failed to load; fix or remove it first", c.Path())
+	}
+	raw, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+	var tree map[string]interface{}
+	if err := json.Unmarshal(raw, &tree); err != nil {
 		return err
 	}
+	restored := c.restoreOverridden(tree)
+	scope := secretScope(c.Path())
+	previous := fileRefs(c.Path())
+	secured := secureTree(tree, scope, previous)
+	if secured || restored {
+		err = writeJSON(c.Path(), tree)
+	} else {
+		err = writeJSON(c.Path(), c)
+	}
+	if err != nil {
+		return err
+	}
+	forgetSecrets(previous, secretRefs(tree), scope)
+	return nil
+}
 
-	data, err := json.MarshalIndent(c, "", "  ")
+func writeJSON(path string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
 	if err != nil {
 		return err
 	}
 
-	return os.WriteFile(path, data, 0644)
+	return writeFileAtomic(path, data, true)
 }
@@ -0,0 +1,281 @@
+package config
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Settings are layered, each layer overriding the one before:
+// defaults < settings.json < profile < FORGER_* environment < -set flags.
+const (
+	LayerDefault = "default"
+	LayerFile    = "file"
+	LayerProfile = "profile"
+	LayerEnv     = "env"
+	LayerFlag    = "flag"
+)
+
+// EnvPrefix starts the environment variables that override settings, e.g.
+// FORGER_WEBHOOK_CYCLE_INTERVAL for webhook.cycle_interval.
+const EnvPrefix = "FORGER_"
+
+// Source records which layer set a value and where, e.g. the file path or
+// the environment variable name.
+type Source struct {
+	Layer  string
+	Detail string
+}
+
+func (s Source) String() string {
+	if s.Detail == "" {
+		return s.Layer
+	}
+	return s.Layer + " " + s.Detail
+}
+
+// Setting is one effective value and the layer it came from.
+type Setting struct {
+	Path   string
+	Value  interface{}
+	Source Source
+}
+
+var (
+	pathOverride  string
+	flagOverrides map[string]string
+)
+
+// SetPath points the app at another settings file. Profiles, secrets and
+// other data files are then kept next to it.
+func SetPath(path string) {
+	pathOverride = path
+}
+
+// SetFlagOverrides installs values from -set key=value flags, keyed by
+// dotted setting path. They apply to every subsequent load.
+func SetFlagOverrides(values map[string]string) {
+	flagOverrides = values
+}
+
+// recordSources marks every leaf in tree as coming from src.
+func recordSources(tree map[string]interface{}, prefix string, src Source, sources map[string]Source) {
+	for key, value := range tree {
+		path := joinPath(prefix, key)
+		if obj, ok := value.(map[string]interface{}); ok && len(obj) > 0 {
+			recordSources(obj, path, src, sources)
+			continue
+		}
+		sources[path] = src
+	}
+}
+
+// applyOverrides layers environment variables and then flag overrides onto
+// merged, recording their sources.
+func applyOverrides(merged map[string]interface{}, sources map[string]Source) error {
+	byEnvName := make(map[string]string)
+	for _, path := range settingPaths(merged) {
+		byEnvName[envName(path)] = path
+	}
+
+	for _, kv := range os.Environ() {
+		name, value, _ := strings.Cut(kv, "=")
+		path, ok := byEnvName[name]
+		if !ok {
+			continue
+		}
+		setPath(merged, path, parseValue(path, value))
+		sources[path] = Source{Layer: LayerEnv, Detail: name}
+	}
+
+	keys := make([]string, 0, len(flagOverrides))
+	for key := range flagOverrides {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, path := range keys {
+		value := parseValue(path, flagOverrides[path])
+		tree := make(map[string]interface{})
+		setPath(tree, path, value)
+		var unknown []string
+		collectUnknown(tree, reflect.TypeOf(Config{}), "", &unknown)
+		if len(unknown) > 0 {
+			return fmt.Errorf("-set %s: unknown setting", path)
+		}
+		setPath(merged, path, value)
+		sources[path] = Source{Layer: LayerFlag, Detail: "-set " + path}
+	}
+
+	return nil
+}
+
+// settingPaths lists every leaf path a setting can have: the fields of the
+// Config type plus any map entries present in tree.
+func settingPaths(tree map[string]interface{}) []string {
+	seen := make(map[string]bool)
+	var walkType func(t reflect.Type, prefix string)
+	walkType = func(t reflect.Type, prefix string) {
+		for key, field := range jsonFields(t) {
+			path := joinPath(prefix, key)
+			if field.Type.Kind() == reflect.Struct {
+				walkType(field.Type, path)
+				continue
+			}
+			seen[path] = true
+		}
+	}
+	walkType(reflect.TypeOf(Config{}), "")
+
+	sources := make(map[string]Source)
+	recordSources(tree, "", Source{}, sources)
+	for path := range sources {
+		seen[path] = true
+	}
+
+	paths := make([]string, 0, len(seen))
+	for path := range seen {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+func envName(path string) string {
+	replacer := strings.NewReplacer(".", "_", "-", "_", " ", "_")
+	return EnvPrefix + strings.ToUpper(replacer.Replace(path))
+}
+
+var textUnmarshaler = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+
+// parseValue reads an override for the setting at path. Settings kept as
+// strings in settings.json, such as URLs and rarities, take the value
+// verbatim, so a token of "123" or "true" stays a string. Others read it as
+// JSON when it parses as such, so numbers, booleans and lists keep their
+// types, and as a plain string otherwise, e.g. a "90s" duration.
+func parseValue(path, s string) interface{} {
+	if t := settingType(path); t != nil && (t.Kind() == reflect.String || reflect.PointerTo(t).Implements(textUnmarshaler)) {
+		return s
+	}
+	var v interface{}
+	if err := json.Unmarshal([]byte(s), &v); err == nil {
+		return v
+	}
+	return s
+}
+
+// settingType returns the Go type of the setting at a dotted path, or nil
+// if Config has no such setting.
+func settingType(path string) reflect.Type {
+	t := reflect.TypeOf(Config{})
+	for _, key := range strings.Split(path, ".") {
+		for t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+		switch t.Kind() {
+		case reflect.Struct:
+			field, ok := jsonFields(t)[key]
+			if !ok {
+				return nil
+			}
+			t = field.Type
+		case reflect.Map:
+			t = t.Elem()
+		default:
+			return nil
+		}
+	}
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t
+}
+
+func setPath(tree map[string]interface{}, path string, value interface{}) {
+	keys := strings.Split(path, ".")
+	for _, key := range keys[:len(keys)-1] {
+		next, ok := tree[key].(map[string]interface{})
+		if !ok {
+			next = make(map[string]interface{})
+			tree[key] = next
+		}
+		tree = next
+	}
+	tree[keys[len(keys)-1]] = value
+}
+
+func lookupPath(tree map[string]interface{}, path string) (interface{}, bool) {
+	keys := strings.Split(path, ".")
+	for _, key := range keys[:len(keys)-1] {
+		next, ok := tree[key].(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		tree = next
+	}
+	value, ok := tree[keys[len(keys)-1]]
+	return value, ok
+}
+
+func deletePath(tree map[string]interface{}, path string) {
+	keys := strings.Split(path, ".")
+	for _, key := range keys[:len(keys)-1] {
+		next, ok := tree[key].(map[string]interface{})
+		if !ok {
+			return
+		}
+		tree = next
+	}
+	delete(tree, keys[len(keys)-1])
+}
+
+func copyTree(tree map[string]interface{}) map[string]interface{} {
+	raw, _ := json.Marshal(tree)
+	var out map[string]interface{}
+	json.Unmarshal(raw, &out)
+	return out
+}
+
+// restoreOverridden puts back the file values for settings that came from
+// the environment or flags, so Save never persists a temporary override.
+// It reports whether anything was restored.
+func (c *Config) restoreOverridden(tree map[string]interface{}) bool {
+	restored := false
+	for path, src := range c.sources {
+		if src.Layer != LayerEnv && src.Layer != LayerFlag {
+			continue
+		}
+		if value, ok := lookupPath(c.persisted, path); ok {
+			setPath(tree, path, value)
+		} else {
+			deletePath(tree, path)
+		}
+		restored = true
+	}
+	return restored
+}
+
+// Explain lists every effective setting with the layer that set it.
+// Secrets are masked.
+func (c *Config) Explain() []Setting {
+	tree := c.Redacted()
+	leaves := make(map[string]Source)
+	recordSources(tree, "", Source{Layer: LayerDefault}, leaves)
+
+	settings := make([]Setting, 0, len(leaves))
+	for path := range leaves {
+		value, _ := lookupPath(tree, path)
+		src, ok := c.sources[path]
+		if !ok {
+			src = Source{Layer: LayerDefault}
+		}
+		settings = append(settings, Setting{Path: path, Value: value, Source: src})
+	}
+	sort.Slice(settings, func(i, j int) bool {
+		return settings[i].Path < settings[j].Path
+	})
+	return settings
+}
@@ -0,0 +1,145 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParseValue(t *testing.T) {
+	tests := []struct {
+		path, value string
+		want        interface{}
+	}{
+		{"webhook.cycle_interval", "5", float64(5)},
+		{"webhook.enabled", "true", true},
+		{"web.port", "not a number", "not a number"},
+		{"preferences.scan_interval", "90s", "90s"},
+		{"preferences.scan_interval", "1.5", 1.5},
+		{"preferences.macro_hotkey", "9", "9"},
+		{"web.bind", "null", "null"},
+		{"webhook.min_rarity", "3", "3"},
+		{"webhook.events.milestone.notifiers", `["a","b"]`, []interface{}{"a", "b"}},
+		{"webhook.events.milestone.min_rarity", "true", "true"},
+		{"regions.ores_panel.x", "12", float64(12)},
+		{"window.width", "800", float64(800)},
+		{"no.such.setting", "true", true},
+	}
+	for _, tt := range tests {
+		if got := parseValue(tt.path, tt.value); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseValue(%q, %q) = %#v, want %#v", tt.path, tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestLayerPrecedence(t *testing.T) {
+	tests := []struct {
+		name                     string
+		file, profile, env, flag string // cycle_interval per layer, "" for unset
+		want                     int
+		layer                    string
+	}{
+		{name: "default", want: 5, layer: LayerDefault},
+		{name: "file", file: "2", want: 2, layer: LayerFile},
+		{name: "profile over file", file: "2", profile: "3", want: 3, layer: LayerProfile},
+		{name: "env over profile", file: "2", profile: "3", env: "4", want: 4, layer: LayerEnv},
+		{name: "flag over env", file: "2", profile: "3", env: "4", flag: "6", want: 6, layer: LayerFlag},
+		{name: "flag over defaults", flag: "7", want: 7, layer: LayerFlag},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useTestSettings(t)
+			writeLayer(t, configPath(), tt.file)
+			writeLayer(t, profilePath("test"), tt.profile)
+			if tt.env != "" {
+				t.Setenv("FORGER_WEBHOOK_CYCLE_INTERVAL", tt.env)
+			}
+			if tt.flag != "" {
+				SetFlagOverrides(map[string]string{"webhook.cycle_interval": tt.flag})
+				t.Cleanup(func() { SetFlagOverrides(nil) })
+			}
+
+			cfg, err := LoadProfile("test")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if cfg.Webhook.CycleInterval != tt.want {
+				t.Errorf("cycle interval = %d, want %d", cfg.Webhook.CycleInterval, tt.want)
+			}
+			setting := explained(t, cfg, "webhook.cycle_interval")
+			if setting.Source.Layer != tt.layer {
+				t.Errorf("source = %v, want layer %s", setting.Source, tt.layer)
+			}
+			if setting.Value != float64(tt.want) {
+				t.Errorf("explained value = %v, want %d", setting.Value, tt.want)
+			}
+		})
+	}
+}
+
+func TestOverridesAreNotSaved(t *testing.T) {
+	useTestSettings(t)
+	writeLayer(t, configPath(), "2")
+	t.Setenv("FORGER_WEB_PORT", "9000")
+	SetFlagOverrides(map[string]string{"webhook.cycle_interval": "8"})
+	t.Cleanup(func() { SetFlagOverrides(nil) })
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cfg.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	SetFlagOverrides(nil)
+	os.Unsetenv("FORGER_WEB_PORT")
+	saved, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if saved.Webhook.CycleInterval != 2 || saved.Web.Port != 8080 {
+		t.Errorf("saved cycle interval %d and port %d, want the file's 2 and the default 8080",
+			saved.Webhook.CycleInterval, saved.Web.Port)
+	}
+}
+
+func TestExplainMasksSecrets(t *testing.T) {
+	useTestSettings(t)
+	cfg := Default()
+	cfg.Webhook.Notifiers = []NotifierConfig{{Name: "d", Type: "discord", URL: "https://example.com/hook"}}
+
+	for _, s := range cfg.Explain() {
+		if value, _ := s.Value.(string); value == "https://example.com/hook" {
+			t.Errorf("%s shows the secret", s.Path)
+		}
+	}
+}
+
+// writeLayer writes a settings file setting only webhook.cycle_interval,
+// or nothing if value is empty.
+func writeLayer(t *testing.T, path, value string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	data := `{"schema_version": 2}`
+	if value != "" {
+		data = `{"schema_version": 2, "webhook": {"cycle_interval": ` + value + `}}`
+	}
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func explained(t *testing.T, cfg *Config, path string) Setting {
+	t.Helper()
+	for _, s := range cfg.Explain() {
+		if s.Path == path {
+			return s
+		}
+	}
+	t.Fatalf("Explain has no %s", path)
+	return Setting{}
+}
@@ -0,0 +1,120 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+)
+
+// SchemaVersion is the settings.json layout written by this build. Bump it
+// and append to migrations whenever a change needs old files rewritten.
+const SchemaVersion = 2
+
+type migration struct {
+	to          int
+	description string
+	apply       func(tree map[string]interface{}) error
+}
+
+var migrations = []migration{
+	{
+		to:          1,
+		description: "drop null sections so defaults fill them in",
+		apply: func(tree map[string]interface{}) error {
+			for key, value := range tree {
+				if value == nil {
+					delete(tree, key)
+				}
+			}
+			return nil
+		},
+	},
+	{
+		to:          2,
+		description: "turn webhook mode/webhook_url/discord_id into a notifier",
+		apply: func(tree map[string]interface{}) error {
+			webhook, ok := tree["webhook"].(map[string]interface{})
+			if !ok {
+				return nil
+			}
+			mode, _ := webhook["mode"].(string)
+			url, _ := webhook["webhook_url"].(string)
+			discordID, _ := webhook["discord_id"].(string)
+			delete(webhook, "mode")
+			delete(webhook, "webhook_url")
+			delete(webhook, "discord_id")
+
+			notifier := map[string]interface{}{"enabled": true}
+			switch {
+			case mode == "webhook" && url != "":
+				notifier["name"], notifier["type"], notifier["url"] = "discord", "discord", url
+			case mode != "webhook" && discordID != "":
+				notifier["name"], notifier["type"], notifier["discord_id"] = "bot", "bot", discordID
+			default:
+				return nil
+			}
+			webhook["notifiers"] = []interface{}{notifier}
+			return nil
+		},
+	},
+}
+
+// migrate upgrades a raw settings tree in place and reports whether any
+// migration ran. A backup of the original bytes is written first.
+func migrate(path string, raw []byte, tree map[string]interface{}) (bool, error) {
+	version := 0
+	if v, ok := tree["schema_version"].(float64); ok {
+		version = int(v)
+	}
+	if version > SchemaVersion {
+		return false, fmt.Errorf("settings schema version %d is newer than this build supports (%d)", version, SchemaVersion)
+	}
+	if version == SchemaVersion {
+		return false, nil
+	}
+
+	backup := fmt.Sprintf("%s.v%d.bak", path, version)
+	if err := os.WriteFile(backup, raw, 0644); err != nil {
+		return false, fmt.Errorf("back up settings before migrating: %w", err)
+	}
+
+	for _, m := range migrations {
+		if m.to <= version {
+			continue
+		}
+		log.Printf("[Config] Migrating settings to v%d: %s", m.to, m.description)
+		if err := m.apply(tree); err != nil {
+			return false, fmt.Errorf("migrate settings to v%d: %w", m.to, err)
+		}
+		version = m.to
+	}
+	tree["schema_version"] = version
+
+	return true, nil
+}
+
+// defaultTree returns Default() as a generic JSON tree.
+func defaultTree() map[string]interface{} {
+	raw, _ := json.Marshal(Default())
+	var tree map[string]interface{}
+	json.Unmarshal(raw, &tree)
+	return tree
+}
+
+// mergeTree overlays src onto dst recursively. Objects are merged key by
+// key; any other value in src replaces the one in dst, except null.
+func mergeTree(dst, src map[string]interface{}) {
+	for key, value := range src {
+		if value == nil {
+			continue
+		}
+		srcObj, srcIsObj := value.(map[string]interface{})
+		dstObj, dstIsObj := dst[key].(map[string]interface{})
+		if srcIsObj && dstIsObj {
+			mergeTree(dstObj, srcObj)
+			continue
+		}
+		dst[key] = value
+	}
+}
@@ -0,0 +1,129 @@
+package config
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestMigrate(t *testing.T) {
+	tests := []struct {
+		name     string
+		in       string
+		migrated bool
+		want     string // tree after migrating, ignored unless migrated
+		wantErr  bool
+	}{
+		{
+			name:     "webhook mode",
+			in:       `{"webhook": {"mode": "webhook", "webhook_url": "https://example.com/hook", "discord_id": "1"}}`,
+			migrated: true,
+			want:     `{"schema_version": 2, "webhook": {"notifiers": [{"enabled": true, "name": "discord", "type": "discord", "url": "https://example.com/hook"}]}}`,
+		},
+		{
+			name:     "bot mode",
+			in:       `{"schema_version": 1, "webhook": {"mode": "bot", "discord_id": "42"}}`,
+			migrated: true,
+			want:     `{"schema_version": 2, "webhook": {"notifiers": [{"enabled": true, "name": "bot", "type": "bot", "discord_id": "42"}]}}`,
+		},
+		{
+			name:     "nothing to notify",
+			in:       `{"schema_version": 1, "webhook": {"mode": "webhook", "cycle_interval": 3}}`,
+			migrated: true,
+			want:     `{"schema_version": 2, "webhook": {"cycle_interval": 3}}`,
+		},
+		{
+			name:     "null sections",
+			in:       `{"regions": null, "webhook": null, "preferences": {"opacity": 80}}`,
+			migrated: true,
+			want:     `{"schema_version": 2, "preferences": {"opacity": 80}}`,
+		},
+		{
+			name: "current",
+			in:   `{"schema_version": 2, "webhook": {"mode": "webhook"}}`,
+		},
+		{
+			name:    "newer than this build",
+			in:      `{"schema_version": 3}`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "settings.json")
+			var tree map[string]interface{}
+			if err := json.Unmarshal([]byte(tt.in), &tree); err != nil {
+				t.Fatal(err)
+			}
+
+			migrated, err := migrate(path, []byte(tt.in), tree)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, want error %v", err, tt.wantErr)
+			}
+			if migrated != tt.migrated {
+				t.Fatalf("migrated = %v, want %v", migrated, tt.migrated)
+			}
+			backups, _ := filepath.Glob(path + ".v*.bak")
+			if !tt.migrated {
+				if len(backups) > 0 {
+					t.Errorf("backed up %v without migrating", backups)
+				}
+				return
+			}
+
+			var want map[string]interface{}
+			if err := json.Unmarshal([]byte(tt.want), &want); err != nil {
+				t.Fatal(err)
+			}
+			got := copyTree(tree) // numbers as float64, like want
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("tree = %v, want %v", got, want)
+			}
+			if len(backups) != 1 {
+				t.Fatalf("backups = %v, want one", backups)
+			}
+			if raw, _ := os.ReadFile(backups[0]); string(raw) != tt.in {
+				t.Errorf("backup = %s, want the original", raw)
+			}
+		})
+	}
+}
+
+func TestLoadMigratesV1File(t *testing.T) {
+	mem := useTestSettings(t)
+	v1 := `{"schema_version": 1, "webhook": {"enabled": true, "mode": "webhook", "webhook_url": "https://example.com/hook"}}`
+	if err := os.WriteFile(configPath(), []byte(v1), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.SchemaVersion != SchemaVersion {
+		t.Errorf("schema version = %d, want %d", cfg.SchemaVersion, SchemaVersion)
+	}
+	if len(cfg.Webhook.Notifiers) != 1 {
+		t.Fatalf("got %d notifiers, want 1", len(cfg.Webhook.Notifiers))
+	}
+	if url, err := cfg.Webhook.Notifiers[0].URL.Value(); err != nil || url != "https://example.com/hook" {
+		t.Errorf("notifier url = %q (err %v)", url, err)
+	}
+
+	raw, err := os.ReadFile(configPath())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var saved Config
+	if err := json.Unmarshal(raw, &saved); err != nil {
+		t.Fatal(err)
+	}
+	if saved.SchemaVersion != SchemaVersion || len(saved.Webhook.Notifiers) != 1 {
+		t.Fatalf("migrated file wasn't saved: %s", raw)
+	}
+	if !saved.Webhook.Notifiers[0].URL.IsRef() || len(mem.values) != 1 {
+		t.Errorf("webhook url saved in plaintext: %s", raw)
+	}
+}
@@ -0,0 +1,149 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var profileNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9 _-]{0,31}$`)
+
+func profilesDir() string {
+	return filepath.Join(Dir(), "profiles")
+}
+
+func profilePath(name string) string {
+	return filepath.Join(profilesDir(), name+".json")
+}
+
+func activeProfilePath() string {
+	return filepath.Join(Dir(), "active_profile")
+}
+
+func checkProfileName(name string) error {
+	if !profileNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid profile name %q: use up to 32 letters, digits, spaces, '-' or '_'", name)
+	}
+	return nil
+}
+
+// Profiles lists the saved profile names in alphabetical order.
+func Profiles() ([]string, error) {
+	entries, err := os.ReadDir(profilesDir())
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, entry := range entries {
+		if name, ok := strings.CutSuffix(entry.Name(), ".json"); ok && !entry.IsDir() {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// ActiveProfile returns the selected profile name, or empty when the base
+// settings are in use.
+func ActiveProfile() string {
+	raw, err := os.ReadFile(activeProfilePath())
+	if err != nil {
+		return ""
+	}
+	name := strings.TrimSpace(string(raw))
+	if checkProfileName(name) != nil {
+		return ""
+	}
+	if _, err := os.Stat(profilePath(name)); err != nil {
+		return ""
+	}
+	return name
+}
+
+func profileExists(name string) bool {
+	_, err := os.Stat(profilePath(name))
+	return err == nil
+}
+
+// CreateProfile adds an empty profile that inherits everything from the
+// base settings until it is changed.
+func CreateProfile(name string) error {
+	if err := checkProfileName(name); err != nil {
+		return err
+	}
+	if profileExists(name) {
+		return fmt.Errorf("profile %q already exists", name)
+	}
+	return writeJSON(profilePath(name), map[string]interface{}{
+		"schema_version": SchemaVersion,
+	})
+}
+
+// CloneProfile copies src into a new profile dst. An empty src copies the
+// base settings.
+func CloneProfile(src, dst string) error {
+	if err := checkProfileName(dst); err != nil {
+		return err
+	}
+	if profileExists(dst) {
+		return fmt.Errorf("profile %q already exists", dst)
+	}
+
+	cfg, err := LoadProfile(src)
+	if err != nil {
+		return err
+	}
+	cfg.profile = dst
+	return cfg.Save()
+}
+
+// DeleteProfile removes a profile. The active profile can't be deleted.
+func DeleteProfile(name string) error {
+	if err := checkProfileName(name); err != nil {
+		return err
+	}
+	if name == ActiveProfile() {
+		return errors.New("can't delete the active profile; switch to another one first")
+	}
+	if err := os.Remove(profilePath(name)); err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf("profile %q does not exist", name)
+		}
+		return err
+	}
+	return nil
+}
+
+// SwitchProfile loads the named profile and makes it the active one. An
+// empty name switches back to the base settings. Nothing changes if the
+// profile fails to load.
+func SwitchProfile(name string) (*Config, error) {
+	if name != "" && !profileExists(name) {
+		return nil, fmt.Errorf("profile %q does not exist", name)
+	}
+
+	cfg, err := LoadProfile(name)
+	if err != nil {
+		return nil, err
+	}
+
+	if name == "" {
+		if err := os.Remove(activeProfilePath()); err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+		rememberHash(activeProfilePath(), nil)
+		return cfg, nil
+	}
+	if err := writeFileAtomic(activeProfilePath(), []byte(name+"\n"), false); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
@@ -0,0 +1,140 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// maxRepairs bounds how many problems repair fixes before giving up.
+const maxRepairs = 50
+
+// repair returns settings decoded from tree with every invalid setting put
+// back to its default, or, where there is no default (a notifier, region
+// or token entry), with the entry holding it removed.
+func repair(tree map[string]interface{}) (*Config, error) {
+	defaults := defaultTree()
+	tree = copyTree(tree)
+	for i := 0; i < maxRepairs; i++ {
+		data, err := json.Marshal(tree)
+		if err != nil {
+			return nil, err
+		}
+		cfg, _, err := Decode(data)
+		if err != nil {
+			return nil, err
+		}
+		err = cfg.Validate()
+		var invalid *ValidationError
+		if !errors.As(err, &invalid) {
+			return cfg, err
+		}
+		// One at a time: removing a list entry renumbers the rest
+		if !repairKey(tree, defaults, splitKey(invalid.Problems[0].Key)) {
+			return nil, err
+		}
+	}
+	return nil, errors.New("too many invalid settings to repair")
+}
+
+// repairKey resets the setting at key to its default, or deletes the
+// first part of key that has no default. It reports whether tree changed.
+func repairKey(tree, defaults map[string]interface{}, key []interface{}) bool {
+	for n := 1; n <= len(key); n++ {
+		def, ok := lookupKey(defaults, key[:n])
+		if !ok {
+			return deleteKey(tree, key[:n])
+		}
+		if n == len(key) {
+			return setKey(tree, key, def)
+		}
+	}
+	return false
+}
+
+// splitKey turns a key such as "webhook.notifiers[0].url" into its parts:
+// strings for object fields and ints for list indexes.
+func splitKey(key string) []interface{} {
+	var parts []interface{}
+	for _, field := range strings.Split(key, ".") {
+		name, rest, _ := strings.Cut(field, "[")
+		parts = append(parts, name)
+		for rest != "" {
+			index, tail, _ := strings.Cut(rest, "]")
+			i, _ := strconv.Atoi(index)
+			parts = append(parts, i)
+			rest = strings.TrimPrefix(tail, "[")
+		}
+	}
+	return parts
+}
+
+func lookupKey(tree interface{}, key []interface{}) (interface{}, bool) {
+	for _, part := range key {
+		switch node := tree.(type) {
+		case map[string]interface{}:
+			name, _ := part.(string)
+			value, ok := node[name]
+			if !ok {
+				return nil, false
+			}
+			tree = value
+		case []interface{}:
+			i, ok := part.(int)
+			if !ok || i < 0 || i >= len(node) {
+				return nil, false
+			}
+			tree = node[i]
+		default:
+			return nil, false
+		}
+	}
+	return tree, true
+}
+
+func setKey(tree map[string]interface{}, key []interface{}, value interface{}) bool {
+	parent, ok := lookupKey(tree, key[:len(key)-1])
+	obj, isObj := parent.(map[string]interface{})
+	name, isName := key[len(key)-1].(string)
+	if !ok || !isObj || !isName {
+		return false
+	}
+	obj[name] = copyValue(value)
+	return true
+}
+
+func deleteKey(tree map[string]interface{}, key []interface{}) bool {
+	parentKey := key[:len(key)-1]
+	parent, ok := lookupKey(tree, parentKey)
+	if !ok {
+		return false
+	}
+	switch node := parent.(type) {
+	case map[string]interface{}:
+		name, _ := key[len(key)-1].(string)
+		if _, ok := node[name]; !ok {
+			return false
+		}
+		delete(node, name)
+		return true
+	case []interface{}:
+		i, _ := key[len(key)-1].(int)
+		if i < 0 || i >= len(node) {
+			return false
+		}
+		list := append(node[:i:i], node[i+1:]...)
+		if len(parentKey) == 0 {
+			return false
+		}
+		return setKey(tree, parentKey, list)
+	}
+	return false
+}
+
+func copyValue(v interface{}) interface{} {
+	raw, _ := json.Marshal(v)
+	var out interface{}
+	json.Unmarshal(raw, &out)
+	return out
+}
@@ -0,0 +1,100 @@
+package config
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestSplitKey(t *testing.T) {
+	tests := []struct {
+		key  string
+		want []interface{}
+	}{
+		{"web.port", []interface{}{"web", "port"}},
+		{"webhook.notifiers[2].url", []interface{}{"webhook", "notifiers", 2, "url"}},
+		{"webhook.capture.masks[0]", []interface{}{"webhook", "capture", "masks", 0}},
+		{"regions.ores_panel", []interface{}{"regions", "ores_panel"}},
+	}
+	for _, tt := range tests {
+		if got := splitKey(tt.key); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("splitKey(%q) = %v, want %v", tt.key, got, tt.want)
+		}
+	}
+}
+
+func TestRepair(t *testing.T) {
+	tests := []struct {
+		name  string
+		in    string
+		check func(*Config) string // returns what is wrong, or ""
+	}{
+		{
+			name: "out of range value",
+			in:   `{"webhook": {"cycle_interval": 0}, "preferences": {"opacity": 5}}`,
+			check: func(c *Config) string {
+				if c.Webhook.CycleInterval != 5 || c.Preferences.Opacity != 95 {
+					return "values weren't reset to their defaults"
+				}
+				return ""
+			},
+		},
+		{
+			name: "invalid list entry",
+			in: `{"webhook": {"notifiers": [
+				{"name": "a", "type": "pigeon"},
+				{"name": "b", "type": "discord", "url": "https://example.com/b"},
+				{"name": "", "type": "discord", "url": "https://example.com/c"}
+			]}}`,
+			check: func(c *Config) string {
+				if len(c.Webhook.Notifiers) != 1 || c.Webhook.Notifiers[0].Name != "b" {
+					return "invalid notifiers weren't removed, or the valid one went with them"
+				}
+				return ""
+			},
+		},
+		{
+			name: "invalid map entry",
+			in:   `{"regions": {"ores_panel": {"x": 1, "y": 2, "width": 0, "height": 10}, "stats": {"x": 0, "y": 0, "width": 5, "height": 5}}}`,
+			check: func(c *Config) string {
+				if _, ok := c.Regions["ores_panel"]; ok || c.Regions["stats"] == nil {
+					return "empty region wasn't removed, or the valid one went with it"
+				}
+				return ""
+			},
+		},
+		{
+			name: "valid",
+			in:   `{"web": {"port": 9000}}`,
+			check: func(c *Config) string {
+				if c.Web.Port != 9000 {
+					return "valid setting was changed"
+				}
+				return ""
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var tree map[string]interface{}
+			if err := json.Unmarshal([]byte(tt.in), &tree); err != nil {
+				t.Fatal(err)
+			}
+			before := copyTree(tree)
+
+			cfg, err := repair(tree)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := cfg.Validate(); err != nil {
+				t.Errorf("repaired settings are invalid: %v", err)
+			}
+			if problem := tt.check(cfg); problem != "" {
+				t.Error(problem)
+			}
+			if !reflect.DeepEqual(tree, before) {
+				t.Error("repair changed the tree it was given")
+			}
+		})
+	}
+}
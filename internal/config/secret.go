@@ -0,0 +1,232 @@
+package config
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"forger-companion/internal/secrets"
+	"log"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// secretPrefix marks a Secret value as a reference into the secrets store
+// rather than the secret itself.
+const secretPrefix = "secret:"
+
+const redacted = "********"
+
+// Secret is a sensitive setting. In settings.json it holds a reference like
+// "secret:settings/webhook.notifiers[0].url#9f86d081e2a4"; a plaintext
+// value typed in by hand is moved into the secrets store the next time the
+// file is loaded. The random suffix makes every stored value's key unique,
+// so a reference keeps pointing at its own value when list entries around
+// it are removed or added.
+type Secret string
+
+func (s Secret) IsRef() bool {
+	return strings.HasPrefix(string(s), secretPrefix)
+}
+
+// Value returns the secret itself, looking references up in the store.
+func (s Secret) Value() (string, error) {
+	if !s.IsRef() {
+		return string(s), nil
+	}
+	key := strings.TrimPrefix(string(s), secretPrefix)
+	value, err := secretStore().Get(key)
+	if err != nil {
+		return "", fmt.Errorf("secret %q: %w", key, err)
+	}
+	return value, nil
+}
+
+var (
+	storeOnce sync.Once
+	store     secrets.Store
+)
+
+func secretStore() secrets.Store {
+	storeOnce.Do(func() {
+		store = secrets.Open(Dir())
+	})
+	return store
+}
+
+// SetSecret stores value under key and returns the reference to put in the
+// config.
+func SetSecret(key, value string) (Secret, error) {
+	if err := secretStore().Set(key, value); err != nil {
+		return "", err
+	}
+	return Secret(secretPrefix + key), nil
+}
+
+var secretType = reflect.TypeOf(Secret(""))
+
+// secureTree moves plaintext Secret values in a settings tree into the
+// store, replacing them with references. scope keeps keys from different
+// files apart. A value already stored under one of previous, the
+// references in the file being replaced, keeps that reference. It reports
+// whether the tree changed.
+func secureTree(tree map[string]interface{}, scope string, previous map[string]bool) bool {
+	changed := false
+	walkSecrets(tree, reflect.TypeOf(Config{}), "", func(obj map[string]interface{}, key, path string) {
+		value, _ := obj[key].(string)
+		if value == "" || Secret(value).IsRef() {
+			return
+		}
+		if ref, ok := storedRef(previous, scope, value); ok {
+			obj[key] = ref
+			changed = true
+			return
+		}
+		ref, err := SetSecret(newSecretKey(scope, path), value)
+		if err != nil {
+			log.Printf("[Config] Can't move %s into the secrets store, leaving it in plaintext: %v", path, err)
+			return
+		}
+		obj[key] = string(ref)
+		changed = true
+	})
+	return changed
+}
+
+// newSecretKey names a new store entry for the secret at path.
+func newSecretKey(scope, path string) string {
+	id := make([]byte, 6)
+	rand.Read(id)
+	return scope + "/" + path + "#" + hex.EncodeToString(id)
+}
+
+// storedRef returns the reference among refs, in scope, that already holds
+// value.
+func storedRef(refs map[string]bool, scope, value string) (string, bool) {
+	for ref := range refs {
+		if !strings.HasPrefix(ref, secretPrefix+scope+"/") {
+			continue
+		}
+		if stored, err := Secret(ref).Value(); err == nil && stored == value {
+			return ref, true
+		}
+	}
+	return "", false
+}
+
+// secretRefs collects the secret references in a settings tree.
+func secretRefs(tree map[string]interface{}) map[string]bool {
+	refs := make(map[string]bool)
+	walkSecrets(tree, reflect.TypeOf(Config{}), "", func(obj map[string]interface{}, key, path string) {
+		if value, _ := obj[key].(string); Secret(value).IsRef() {
+			refs[value] = true
+		}
+	})
+	return refs
+}
+
+// fileRefs returns the secret references in the settings file at path, or
+// none if it can't be read.
+func fileRefs(path string) map[string]bool {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	var tree map[string]interface{}
+	if json.Unmarshal(raw, &tree) != nil {
+		return nil
+	}
+	return secretRefs(tree)
+}
+
+// forgetSecrets deletes the store entries of scope that were referenced
+// before but no longer are, after an entry was removed or its secret
+// replaced. Entries of other scopes, such as those a cloned profile still
+// shares with its source, are left alone.
+func forgetSecrets(before, after map[string]bool, scope string) {
+	for ref := range before {
+		key := strings.TrimPrefix(ref, secretPrefix)
+		if after[ref] || !strings.HasPrefix(key, scope+"/") {
+			continue
+		}
+		if err := secretStore().Delete(key); err != nil {
+			log.Printf("[Config] Can't delete unused secret %q: %v", key, err)
+		}
+	}
+}
+
+// Redacted returns the settings as a JSON tree with every plaintext secret
+// masked. References are kept since they reveal nothing.
+func (c *Config) Redacted() map[string]interface{} {
+	raw, _ := json.Marshal(c)
+	var tree map[string]interface{}
+	json.Unmarshal(raw, &tree)
+
+	walkSecrets(tree, reflect.TypeOf(Config{}), "", func(obj map[string]interface{}, key, path string) {
+		if value, _ := obj[key].(string); value != "" && !Secret(value).IsRef() {
+			obj[key] = redacted
+		}
+	})
+	return tree
+}
+
+// walkSecrets calls fn for every Secret-typed field present in tree.
+func walkSecrets(value interface{}, t reflect.Type, path string, fn func(obj map[string]interface{}, key, path string)) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		obj, ok := value.(map[string]interface{})
+		if !ok {
+			return
+		}
+		for key, field := range jsonFields(t) {
+			v, ok := obj[key]
+			if !ok {
+				continue
+			}
+			if field.Type == secretType {
+				fn(obj, key, joinPath(path, key))
+				continue
+			}
+			walkSecrets(v, field.Type, joinPath(path, key), fn)
+		}
+	case reflect.Map:
+		obj, ok := value.(map[string]interface{})
+		if !ok {
+			return
+		}
+		for key, v := range obj {
+			if t.Elem() == secretType {
+				fn(obj, key, joinPath(path, key))
+				continue
+			}
+			walkSecrets(v, t.Elem(), joinPath(path, key), fn)
+		}
+	case reflect.Slice:
+		items, ok := value.([]interface{})
+		if !ok {
+			return
+		}
+		for i, v := range items {
+			walkSecrets(v, t.Elem(), fmt.Sprintf("%s[%d]", path, i), fn)
+		}
+	}
+}
+
+// secretScope names the file a secret came from, so profiles can hold
+// different webhook URLs. Profile scopes are prefixed so a profile named
+// "settings" doesn't share keys with the base settings. References already
+// written keep their key, so changing scopes needs no migration.
+func secretScope(path string) string {
+	name := strings.TrimSuffix(filepath.Base(path), ".json")
+	if filepath.Dir(path) == profilesDir() {
+		return "profiles/" + name
+	}
+	return name
+}
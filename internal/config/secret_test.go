@@ -0,0 +1,137 @@
+package config
+
+import (
+	"forger-companion/internal/secrets"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// memStore keeps secrets in memory so tests don't touch the keyring.
+type memStore struct {
+	mu     sync.Mutex
+	values map[string]string
+}
+
+func (m *memStore) Get(key string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	value, ok := m.values[key]
+	if !ok {
+		return "", secrets.ErrNotFound
+	}
+	return value, nil
+}
+
+func (m *memStore) Set(key, value string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.values[key] = value
+	return nil
+}
+
+func (m *memStore) Delete(key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.values, key)
+	return nil
+}
+
+// useTestSettings points the package at a settings file in a temp dir and
+// an in-memory secrets store.
+func useTestSettings(t *testing.T) *memStore {
+	t.Helper()
+	SetPath(filepath.Join(t.TempDir(), "settings.json"))
+	t.Cleanup(func() { SetPath("") })
+
+	mem := &memStore{values: make(map[string]string)}
+	storeOnce.Do(func() {})
+	old := store
+	store = mem
+	t.Cleanup(func() { store = old })
+	return mem
+}
+
+func TestSecretsSurviveRemovedEntries(t *testing.T) {
+	mem := useTestSettings(t)
+
+	cfg := Default()
+	a, err := cfg.Web.AddToken("a", "control")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := cfg.Web.AddToken("b", "read")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg = saveAndLoad(t, cfg)
+	refA := cfg.Web.Tokens[0].Token
+
+	cfg.Web.Tokens = cfg.Web.Tokens[1:]
+	cfg = saveAndLoad(t, cfg)
+	c, err := cfg.Web.AddToken("c", "read")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg = saveAndLoad(t, cfg)
+
+	want := map[string]string{"b": b, "c": c}
+	if len(cfg.Web.Tokens) != len(want) {
+		t.Fatalf("got %d tokens, want %d", len(cfg.Web.Tokens), len(want))
+	}
+	for _, tok := range cfg.Web.Tokens {
+		if !tok.Token.IsRef() {
+			t.Errorf("token %s saved in plaintext", tok.Name)
+		}
+		got, err := tok.Token.Value()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != want[tok.Name] {
+			t.Errorf("token %s resolves to %q, want %q", tok.Name, got, want[tok.Name])
+		}
+		if tok.Token == refA {
+			t.Errorf("token %s reuses removed token's key", tok.Name)
+		}
+	}
+	if _, err := mem.Get(strings.TrimPrefix(string(refA), secretPrefix)); err == nil {
+		t.Errorf("removed token %q is still stored", a)
+	}
+}
+
+func TestReplacedSecretIsDeleted(t *testing.T) {
+	mem := useTestSettings(t)
+
+	cfg := Default()
+	cfg.Web.Tokens = []WebToken{{Name: "a", Token: "first", Scope: "read"}}
+	cfg = saveAndLoad(t, cfg)
+	old := cfg.Web.Tokens[0].Token
+
+	// Saving again without a change keeps the reference.
+	cfg = saveAndLoad(t, cfg)
+	if cfg.Web.Tokens[0].Token != old {
+		t.Errorf("unchanged token moved from %q to %q", old, cfg.Web.Tokens[0].Token)
+	}
+
+	cfg.Web.Tokens[0].Token = "second"
+	cfg = saveAndLoad(t, cfg)
+	if got, _ := cfg.Web.Tokens[0].Token.Value(); got != "second" {
+		t.Errorf("token = %q, want %q", got, "second")
+	}
+	if len(mem.values) != 1 {
+		t.Errorf("store holds %d secrets, want 1", len(mem.values))
+	}
+}
+
+func saveAndLoad(t *testing.T, cfg *Config) *Config {
+	t.Helper()
+	if err := cfg.Save(); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return loaded
+}
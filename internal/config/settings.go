@@ -0,0 +1,380 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"forger-companion/internal/events"
+	"forger-companion/internal/msgtemplate"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+type MacroSettings struct {
+	Enabled      bool    `json:"enabled"`
+	HoldDuration Minutes `json:"hold_duration"`
+	AutoSell     bool    `json:"auto_sell"`
+}
+
+type Preferences struct {
+	AutoMode      bool    `json:"auto_mode"`
+	AlwaysOnTop   bool    `json:"always_on_top"`
+	AutoSwitchTab bool    `json:"auto_switch_tab"`
+	Opacity       Percent `json:"opacity"`
+	ScanInterval  Seconds `json:"scan_interval"`
+	MacroHotkey   string  `json:"macro_hotkey"`
+}
+
+// Minutes is a duration stored in settings.json as a number of minutes.
+// Strings such as "90s" are accepted too.
+type Minutes time.Duration
+
+func (m Minutes) Duration() time.Duration { return time.Duration(m) }
+
+func (m Minutes) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(m).Minutes())
+}
+
+func (m *Minutes) UnmarshalJSON(b []byte) error {
+	d, err := parseDuration(b, time.Minute)
+	*m = Minutes(d)
+	return err
+}
+
+// Seconds is a duration stored in settings.json as a number of seconds.
+type Seconds time.Duration
+
+func (s Seconds) Duration() time.Duration { return time.Duration(s) }
+
+func (s Seconds) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(s).Seconds())
+}
+
+func (s *Seconds) UnmarshalJSON(b []byte) error {
+	d, err := parseDuration(b, time.Second)
+	*s = Seconds(d)
+	return err
+}
+
+func parseDuration(b []byte, unit time.Duration) (time.Duration, error) {
+	var n float64
+	if err := json.Unmarshal(b, &n); err == nil {
+		return time.Duration(n * float64(unit)), nil
+	}
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return 0, fmt.Errorf("want a number of %s or a duration string, got %s", unitName(unit), b)
+	}
+	return time.ParseDuration(s)
+}
+
+func unitName(unit time.Duration) string {
+	if unit == time.Minute {
+		return "minutes"
+	}
+	return "seconds"
+}
+
+// Percent is a whole percentage from 0 to 100.
+type Percent int
+
+// Problem is one invalid setting, named by its dotted path.
+type Problem struct {
+	Key     string
+	Message string
+}
+
+// ValidationError lists every invalid setting found by Validate.
+type ValidationError struct {
+	Problems []Problem
+}
+
+func (e *ValidationError) Error() string {
+	lines := make([]string, len(e.Problems))
+	for i, p := range e.Problems {
+		lines[i] = p.Key + ": " + p.Message
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Validate checks every setting and returns all problems as a
+// *ValidationError.
+func (c *Config) Validate() error {
+	var problems []Problem
+	add := func(key, format string, args ...interface{}) {
+		problems = append(problems, Problem{Key: key, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if d := c.MacroSettings.HoldDuration.Duration(); d <= 0 || d > 24*time.Hour {
+		add("macro_settings.hold_duration", "must be between 0 and 1440 minutes, got %v", d)
+	}
+
+	if d := c.Preferences.ScanInterval.Duration(); d < 200*time.Millisecond || d > time.Minute {
+		add("preferences.scan_interval", "must be between 0.2 and 60 seconds, got %v", d)
+	}
+	if o := c.Preferences.Opacity; o < 10 || o > 100 {
+		add("preferences.opacity", "must be between 10 and 100 percent, got %d", o)
+	}
+	if c.Preferences.MacroHotkey == "" {
+		add("preferences.macro_hotkey", "must not be empty")
+	}
+
+	for name, region := range c.Regions {
+		if region != nil && (region.Width <= 0 || region.Height <= 0) {
+			add("regions."+name, "width and height must be positive")
+		}
+	}
+	for name, button := range c.MacroButtons {
+		if button != nil && button.Key == nil && (button.X == nil || button.Y == nil) {
+			add("macro_buttons."+name, "needs either a key or both x and y")
+		}
+	}
+
+	w := c.Webhook
+	names := make(map[string]bool)
+	for i, n := range w.Notifiers {
+		key := fmt.Sprintf("webhook.notifiers[%d]", i)
+		if n.Name == "" {
+			add(key+".name", "must not be empty")
+		} else if names[n.Name] {
+			add(key+".name", "duplicate notifier name %q", n.Name)
+		}
+		names[n.Name] = true
+
+		switch n.Type {
+		case "discord", "slack", "ntfy", "json":
+			if n.Enabled && n.URL == "" {
+				add(key+".url", "required for %s notifiers", n.Type)
+			}
+		case "bot":
+			if n.Enabled && n.DiscordID == "" {
+				add(key+".discord_id", "required for bot notifiers")
+			}
+			if n.APIBase != "" && !strings.HasPrefix(n.APIBase, "http://") && !strings.HasPrefix(n.APIBase, "https://") {
+				add(key+".api_base", "must be an http:// or https:// URL")
+			}
+		case "telegram":
+			if n.Enabled && (n.Token == "" || n.ChatID == "") {
+				add(key, "telegram notifiers need token and chat_id")
+			}
+		default:
+			add(key+".type", "unknown type %q (want one of %s)", n.Type, strings.Join(NotifierTypes, ", "))
+		}
+		validateTemplates(key+".templates", n.Templates, add)
+	}
+	if w.CycleInterval < 1 {
+		add("webhook.cycle_interval", "must be at least 1, got %d", w.CycleInterval)
+	}
+	if w.GIFFrames < 1 || w.GIFFrames > 50 {
+		add("webhook.gif_frames", "must be between 1 and 50, got %d", w.GIFFrames)
+	}
+	if w.GIFDuration < 0 {
+		add("webhook.gif_duration", "must not be negative, got %d", w.GIFDuration)
+	}
+	if w.MinRarity != 0 && !w.MinRarity.Valid() {
+		add("webhook.min_rarity", "invalid rarity")
+	}
+	if w.NotifyMinRarity != 0 && !w.NotifyMinRarity.Valid() {
+		add("webhook.notify_min_rarity", "invalid rarity")
+	}
+	if w.MoneyMilestone < 0 {
+		add("webhook.money_milestone", "must not be negative, got %d", w.MoneyMilestone)
+	}
+	validateTemplates("webhook.templates", w.Templates, add)
+	c.validateCapture(add)
+	for kind, rule := range w.Events {
+		key := "webhook.events." + kind
+		if !knownEvent(kind) {
+			add(key, "unknown event")
+			continue
+		}
+		if rule.Cooldown < 0 {
+			add(key+".cooldown", "must not be negative")
+		}
+		if rule.MinRarity != 0 && !rule.MinRarity.Valid() {
+			add(key+".min_rarity", "invalid rarity")
+		}
+		for _, name := range rule.Notifiers {
+			if !names[name] {
+				add(key+".notifiers", "no notifier named %q", name)
+			}
+		}
+	}
+
+	c.validateWeb(add)
+
+	if len(problems) > 0 {
+		return &ValidationError{Problems: problems}
+	}
+	return nil
+}
+
+func (c *Config) validateCapture(add func(key, format string, args ...interface{})) {
+	capture := c.Webhook.Capture
+	switch capture.Mode {
+	case "fullscreen", "window":
+	case "region":
+		if capture.Region == "" {
+			add("webhook.capture.region", "required when mode is \"region\"")
+		}
+	default:
+		add("webhook.capture.mode", "unknown mode %q (want one of %s)", capture.Mode, strings.Join(CaptureModes, ", "))
+	}
+	if capture.Scale < 0.1 || capture.Scale > 1 {
+		add("webhook.capture.scale", "must be between 0.1 and 1, got %g", capture.Scale)
+	}
+	if capture.Resample != "box" && capture.Resample != "bilinear" {
+		add("webhook.capture.resample", "must be \"box\" or \"bilinear\", got %q", capture.Resample)
+	}
+	if capture.Format != "png" && capture.Format != "jpeg" {
+		add("webhook.capture.format", "must be \"png\" or \"jpeg\", got %q", capture.Format)
+	}
+	if capture.JPEGQuality < 1 || capture.JPEGQuality > 100 {
+		add("webhook.capture.jpeg_quality", "must be between 1 and 100, got %d", capture.JPEGQuality)
+	}
+	for i, mask := range capture.Masks {
+		key := fmt.Sprintf("webhook.capture.masks[%d]", i)
+		if mask.Style != "blur" && mask.Style != "blackout" {
+			add(key+".style", "must be \"blur\" or \"blackout\", got %q", mask.Style)
+		}
+		if mask.Region == "" && (mask.Width <= 0 || mask.Height <= 0) {
+			add(key, "needs a region name or a positive width and height")
+		}
+	}
+}
+
+// validateWeb checks the server address and the paired tokens.
+func (c *Config) validateWeb(add func(key, format string, args ...interface{})) {
+	web := c.Web
+	if web.Port < 1 || web.Port > 65535 {
+		add("web.port", "must be between 1 and 65535, got %d", web.Port)
+	}
+	if web.Bind == "" {
+		add("web.bind", "must not be empty")
+	}
+
+	names := make(map[string]bool)
+	for i, t := range web.Tokens {
+		key := fmt.Sprintf("web.tokens[%d]", i)
+		if t.Name == "" {
+			add(key+".name", "must not be empty")
+		} else if names[t.Name] {
+			add(key+".name", "duplicate token name %q", t.Name)
+		}
+		names[t.Name] = true
+		if t.Token == "" {
+			add(key+".token", "must not be empty")
+		}
+		if t.Scope != "read" && t.Scope != "control" {
+			add(key+".scope", "must be \"read\" or \"control\", got %q", t.Scope)
+		}
+	}
+}
+
+// validateTemplates renders each template against sample data so mistakes
+// show up when settings load rather than when a message is sent.
+func validateTemplates(key string, templates map[string]msgtemplate.Template, add func(key, format string, args ...interface{})) {
+	for event, t := range templates {
+		if event != "progress" && !knownEvent(event) {
+			add(key+"."+event, "unknown event")
+			continue
+		}
+		if err := t.Validate(event); err != nil {
+			add(key+"."+event, "%v", err)
+		}
+	}
+}
+
+func knownEvent(kind string) bool {
+	for _, k := range events.Kinds {
+		if string(k) == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// Decode parses settings JSON and also returns the dotted paths of any keys
+// that don't correspond to a known setting, so typos can be reported.
+func Decode(raw []byte) (*Config, []string, error) {
+	cfg := Default()
+	if err := json.Unmarshal(raw, cfg); err != nil {
+		return nil, nil, err
+	}
+
+	var tree interface{}
+	if err := json.Unmarshal(raw, &tree); err != nil {
+		return nil, nil, err
+	}
+	var unknown []string
+	collectUnknown(tree, reflect.TypeOf(cfg).Elem(), "", &unknown)
+	sort.Strings(unknown)
+
+	return cfg, unknown, nil
+}
+
+func collectUnknown(value interface{}, t reflect.Type, path string, unknown *[]string) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		obj, ok := value.(map[string]interface{})
+		if !ok {
+			return
+		}
+		fields := jsonFields(t)
+		for key, v := range obj {
+			field, ok := fields[key]
+			if !ok {
+				*unknown = append(*unknown, joinPath(path, key))
+				continue
+			}
+			collectUnknown(v, field.Type, joinPath(path, key), unknown)
+		}
+	case reflect.Map:
+		obj, ok := value.(map[string]interface{})
+		if !ok {
+			return
+		}
+		for key, v := range obj {
+			collectUnknown(v, t.Elem(), joinPath(path, key), unknown)
+		}
+	case reflect.Slice:
+		items, ok := value.([]interface{})
+		if !ok {
+			return
+		}
+		for i, v := range items {
+			collectUnknown(v, t.Elem(), fmt.Sprintf("%s[%d]", path, i), unknown)
+		}
+	}
+}
+
+func jsonFields(t reflect.Type) map[string]reflect.StructField {
+	fields := make(map[string]reflect.StructField)
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		name := strings.Split(f.Tag.Get("json"), ",")[0]
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		fields[name] = f
+	}
+	return fields
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
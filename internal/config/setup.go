@@ -0,0 +1,34 @@
+package config
+
+// SellButtons are the buttons auto-sell clicks, in order.
+var SellButtons = []string{"sell_tab", "select_all", "accept", "yes_confirm", "close_menu"}
+
+// MissingSetup lists the buttons and regions first-run setup still has to
+// capture: the break position, inventory and ores panel, plus the sell
+// buttons when auto-sell is on. Only the inventory may be a key.
+func (c *Config) MissingSetup() []string {
+	var missing []string
+	needPosition := func(name string) {
+		if b := c.MacroButtons[name]; b == nil || !b.HasPosition() {
+			missing = append(missing, name)
+		}
+	}
+
+	needPosition("break_position")
+	if inv := c.MacroButtons["inventory"]; inv == nil || (inv.Key == nil && !inv.HasPosition()) {
+		missing = append(missing, "inventory")
+	}
+	if c.MacroSettings.AutoSell {
+		for _, name := range SellButtons {
+			needPosition(name)
+		}
+	}
+	if c.Regions["ores_panel"] == nil {
+		missing = append(missing, "ores_panel")
+	}
+	return missing
+}
+
+func (b *MacroButton) HasPosition() bool {
+	return b.X != nil && b.Y != nil
+}
@@ -0,0 +1,93 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Clone returns a deep copy of the settings, to change and publish in
+// place of c while others keep reading c.
+func (c *Config) Clone() *Config {
+	raw, err := json.Marshal(c)
+	if err != nil {
+		panic(fmt.Sprintf("config: clone: %v", err))
+	}
+	next, _, err := Decode(raw)
+	if err != nil {
+		panic(fmt.Sprintf("config: clone: %v", err))
+	}
+	next.profile = c.profile
+	next.sources = make(map[string]Source, len(c.sources))
+	for path, src := range c.sources {
+		next.sources[path] = src
+	}
+	next.persisted = copyTree(c.persisted)
+	next.loadErr = c.loadErr
+	return next
+}
+
+// Update returns a copy of the settings with patch, a partial settings
+// JSON object, merged in and validated. Masked secrets ("********") in the
+// patch are ignored so a client can send back what GET /api/config gave it.
+// The result is not saved.
+func (c *Config) Update(patch []byte) (*Config, error) {
+	var p map[string]interface{}
+	if err := json.Unmarshal(patch, &p); err != nil {
+		return nil, fmt.Errorf("settings patch must be a JSON object: %w", err)
+	}
+
+	walkSecrets(p, reflect.TypeOf(Config{}), "", func(obj map[string]interface{}, key, path string) {
+		if value, _ := obj[key].(string); value == redacted {
+			delete(obj, key)
+		}
+	})
+
+	var unknown []string
+	collectUnknown(p, reflect.TypeOf(Config{}), "", &unknown)
+	if len(unknown) > 0 {
+		return nil, fmt.Errorf("unknown settings: %s", strings.Join(unknown, ", "))
+	}
+
+	raw, err := json.Marshal(c)
+	if err != nil {
+		return nil, err
+	}
+	var tree map[string]interface{}
+	if err := json.Unmarshal(raw, &tree); err != nil {
+		return nil, err
+	}
+	mergeTree(tree, p)
+
+	merged, err := json.Marshal(tree)
+	if err != nil {
+		return nil, err
+	}
+	next, _, err := Decode(merged)
+	if err != nil {
+		return nil, err
+	}
+	if err := next.Validate(); err != nil {
+		return nil, err
+	}
+
+	// Patched values now belong to the settings file, not an override
+	layer := LayerFile
+	if c.profile != "" {
+		layer = LayerProfile
+	}
+	next.profile = c.profile
+	next.sources = make(map[string]Source, len(c.sources))
+	for path, src := range c.sources {
+		next.sources[path] = src
+	}
+	recordSources(p, "", Source{Layer: layer, Detail: c.Path()}, next.sources)
+	next.persisted = copyTree(c.persisted)
+	if next.persisted == nil {
+		next.persisted = make(map[string]interface{})
+	}
+	mergeTree(next.persisted, p)
+	next.loadErr = c.loadErr
+	return next, nil
+}
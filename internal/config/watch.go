@@ -0,0 +1,142 @@
+package config
+
+import (
+	"crypto/sha256"
+	"log"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Watcher polls the settings files for edits made outside the app, reloads
+// them and publishes the result to subscribers. Invalid edits are logged
+// and ignored so a half-typed file never reaches the macro.
+//
+// Published settings are never changed afterwards: a change is made on a
+// Clone and published with Replace, so readers need no lock.
+type Watcher struct {
+	cur      atomic.Pointer[Config]
+	interval time.Duration
+
+	mu          sync.Mutex
+	subscribers []func(*Config)
+	stopChan    chan bool
+}
+
+func NewWatcher(cfg *Config) *Watcher {
+	w := &Watcher{interval: time.Second}
+	w.cur.Store(cfg)
+	return w
+}
+
+// Current returns the settings last published.
+func (w *Watcher) Current() *Config {
+	return w.cur.Load()
+}
+
+// Subscribe registers fn to be called with the new settings after every
+// change, whether from an external edit or from Replace.
+func (w *Watcher) Subscribe(fn func(*Config)) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.subscribers = append(w.subscribers, fn)
+}
+
+func (w *Watcher) Start() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.stopChan != nil {
+		return
+	}
+	w.stopChan = make(chan bool)
+
+	for _, path := range w.paths() {
+		w.changed(path) // seed hashes with what's on disk now
+	}
+	go w.run(w.stopChan)
+}
+
+func (w *Watcher) Stop() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.stopChan != nil {
+		close(w.stopChan)
+		w.stopChan = nil
+	}
+}
+
+// Replace publishes next as the current settings. next must not be
+// changed afterwards.
+func (w *Watcher) Replace(next *Config) {
+	w.mu.Lock()
+	w.cur.Store(next)
+	subscribers := append([]func(*Config){}, w.subscribers...)
+	w.mu.Unlock()
+
+	for _, fn := range subscribers {
+		fn(next)
+	}
+}
+
+func (w *Watcher) run(stop chan bool) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			w.poll()
+		case <-stop:
+			return
+		}
+	}
+}
+
+func (w *Watcher) paths() []string {
+	paths := []string{configPath(), activeProfilePath()}
+	if profile := ActiveProfile(); profile != "" {
+		paths = append(paths, profilePath(profile))
+	}
+	return paths
+}
+
+func (w *Watcher) poll() {
+	changed := false
+	for _, path := range w.paths() {
+		if w.changed(path) {
+			changed = true
+		}
+	}
+	if !changed {
+		return
+	}
+
+	profile := ActiveProfile()
+	next, err := LoadProfile(profile)
+	if err != nil {
+		log.Printf("[Config] Ignoring external edit: %v", err)
+		return
+	}
+
+	log.Printf("[Config] Reloaded settings after external edit")
+	w.Replace(next)
+}
+
+// changed reports whether path's content differs from what was last seen
+// or written by this process.
+func (w *Watcher) changed(path string) bool {
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return false
+	}
+	hash := sha256.Sum256(data)
+
+	written.Lock()
+	defer written.Unlock()
+	if prev, ok := written.hashes[path]; ok && prev == hash {
+		return false
+	}
+	written.hashes[path] = hash
+	return true
+}
@@ -0,0 +1,86 @@
+package config
+
+import (
+	"os"
+	"testing"
+)
+
+func TestWatcherIgnoresOwnWrites(t *testing.T) {
+	useTestSettings(t)
+	path := configPath()
+	w := NewWatcher(Default())
+
+	tests := []struct {
+		name   string
+		change func() error
+		want   bool
+	}{
+		{"first look", func() error { return nil }, true},
+		{"unchanged", func() error { return nil }, false},
+		{"own save", func() error { return writeFileAtomic(path, []byte(`{"web":{"port":8081}}`), true) }, false},
+		{"external edit", func() error { return os.WriteFile(path, []byte(`{"web":{"port":8082}}`), 0644) }, true},
+		{"unchanged after edit", func() error { return nil }, false},
+		{"deleted", func() error { return os.Remove(path) }, true},
+	}
+	for _, tt := range tests {
+		if err := tt.change(); err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if got := w.changed(path); got != tt.want {
+			t.Errorf("%s: changed = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestWatcherPublishesExternalEdits(t *testing.T) {
+	useTestSettings(t)
+	cfg := Default()
+	if err := cfg.Save(); err != nil {
+		t.Fatal(err)
+	}
+	w := NewWatcher(cfg)
+	var published []*Config
+	w.Subscribe(func(c *Config) { published = append(published, c) })
+	for _, path := range w.paths() {
+		w.changed(path)
+	}
+
+	tests := []struct {
+		name string
+		edit func() error
+		want int // cycle interval published, 0 for none
+	}{
+		{"own save", func() error {
+			next := w.Current().Clone()
+			next.Webhook.CycleInterval = 6
+			return next.Save()
+		}, 0},
+		{"external edit", func() error {
+			return os.WriteFile(configPath(), []byte(`{"schema_version": 2, "webhook": {"cycle_interval": 7}}`), 0644)
+		}, 7},
+		{"invalid edit", func() error {
+			return os.WriteFile(configPath(), []byte(`{"schema_version": 2, "webhook": {"cycle_interval": 0}}`), 0644)
+		}, 0},
+		{"half-written edit", func() error {
+			return os.WriteFile(configPath(), []byte(`{"webhook": {`), 0644)
+		}, 0},
+	}
+	for _, tt := range tests {
+		published = nil
+		if err := tt.edit(); err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		w.poll()
+
+		switch {
+		case tt.want == 0 && len(published) > 0:
+			t.Errorf("%s: published settings", tt.name)
+		case tt.want != 0 && len(published) != 1:
+			t.Errorf("%s: published %d times, want once", tt.name, len(published))
+		case tt.want != 0 && published[0].Webhook.CycleInterval != tt.want:
+			t.Errorf("%s: cycle interval = %d, want %d", tt.name, published[0].Webhook.CycleInterval, tt.want)
+		case tt.want != 0 && w.Current() != published[0]:
+			t.Errorf("%s: published settings aren't current", tt.name)
+		}
+	}
+}
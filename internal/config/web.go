@@ -0,0 +1,78 @@
+package config
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"strconv"
+)
+
+// Loopback reports whether the web server only accepts connections from
+// this computer.
+func (w WebSettings) Loopback() bool {
+	if w.Bind == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(w.Bind)
+	return ip != nil && ip.IsLoopback()
+}
+
+// AuthRequired reports whether web clients must present a token. Tokens
+// are always checked once any exist, and can't be skipped off loopback.
+func (w WebSettings) AuthRequired() bool {
+	return len(w.Tokens) > 0 || !w.Loopback()
+}
+
+// Addr is the host:port to listen on.
+func (w WebSettings) Addr() string {
+	return net.JoinHostPort(w.Bind, strconv.Itoa(w.Port))
+}
+
+// URL is where another device reaches the web UI: the bind address, or
+// this computer's LAN address when listening on all interfaces.
+func (w WebSettings) URL() string {
+	host := w.Bind
+	if ip := net.ParseIP(host); ip != nil && ip.IsUnspecified() {
+		if lan := LANAddress(); lan != nil {
+			host = lan.String()
+		}
+	}
+	scheme := "http"
+	if w.TLS {
+		scheme = "https"
+	}
+	return fmt.Sprintf("%s://%s/", scheme, net.JoinHostPort(host, strconv.Itoa(w.Port)))
+}
+
+// LANAddress returns the first private IPv4 address of this computer, or
+// nil if it has none.
+func LANAddress() net.IP {
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return nil
+	}
+	for _, addr := range addrs {
+		if ipnet, ok := addr.(*net.IPNet); ok && ipnet.IP.To4() != nil && ipnet.IP.IsPrivate() {
+			return ipnet.IP
+		}
+	}
+	return nil
+}
+
+// AddToken creates a token for a new device and returns its value. The
+// caller saves the settings, which moves the token into the secrets store.
+func (w *WebSettings) AddToken(name, scope string) (string, error) {
+	for _, t := range w.Tokens {
+		if t.Name == name {
+			return "", fmt.Errorf("a token named %q already exists", name)
+		}
+	}
+	buf := make([]byte, 24)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(buf)
+	w.Tokens = append(w.Tokens, WebToken{Name: name, Token: Secret(token), Scope: scope})
+	return token, nil
+}
@@ -1,35 +1,249 @@
 package data
 
+import (
+	"encoding/json"
+	"fmt"
+	"image/color"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Ore is one ore the forge accepts. The built-in table only holds what the
+// forge shows; sell prices, zones and traits vary with game updates and are
+// left for ores.json to fill in.
 type Ore struct {
-	Name       string
-	Rarity     string
-	Multiplier float64
+	Name       string     `json:"name"`
+	Rarity     Rarity     `json:"rarity"`
+	Multiplier float64    `json:"multiplier"`
+	Aliases    []string   `json:"aliases,omitempty"`    // OCR misreads and short names
+	Color      color.RGBA `json:"-"`                    // rarity color shown in the inventory
+	SellPrice  int        `json:"sell_price,omitempty"` // per-unit sell price, 0 if unknown
+	Zone       string     `json:"zone,omitempty"`       // where the ore drops
+	Traits     []string   `json:"traits,omitempty"`
 }
 
 var Ores = map[string]Ore{
-	"Coal Ore":         {"Coal Ore", "common", 1.0},
-	"Copper Ore":       {"Copper Ore", "common", 1.1},
-	"Iron Ore":         {"Iron Ore", "common", 1.2},
-	"Tin Ore":          {"Tin Ore", "uncommon", 1.3},
-	"Silver Ore":       {"Silver Ore", "uncommon", 1.4},
-	"Gold Ore":         {"Gold Ore", "uncommon", 1.5},
-	"Topaz Ore":        {"Topaz Ore", "rare", 1.6},
-	"Emerald Ore":      {"Emerald Ore", "rare", 1.7},
-	"Ruby Ore":         {"Ruby Ore", "rare", 1.8},
-	"Sapphire Ore":     {"Sapphire Ore", "legendary", 2.0},
-	"Titanium Ore":     {"Titanium Ore", "legendary", 2.2},
-	"Orichalcum Ore":   {"Orichalcum Ore", "legendary", 2.4},
-	"Mythril Ore":      {"Mythril Ore", "mythical", 2.6},
-	"Adamantite Ore":   {"Adamantite Ore", "mythical", 2.8},
-	"Eye Ore":          {"Eye Ore", "epic", 1.9},
-	"Rivalite Ore":     {"Rivalite Ore", "rare", 1.75},
-	"Magmaite Ore":     {"Magmaite Ore", "epic", 1.95},
-}
-
-var LegendaryMythic = []string{
-	"Sapphire Ore",
-	"Titanium Ore",
-	"Orichalcum Ore",
-	"Mythril Ore",
-	"Adamantite Ore",
+	"Coal Ore": {
+		Name: "Coal Ore", Rarity: Common, Multiplier: 1.0, Color: Common.Color(),
+		Aliases: []string{"coal", "c0al", "cool ore"},
+	},
+	"Copper Ore": {
+		Name: "Copper Ore", Rarity: Common, Multiplier: 1.1, Color: Common.Color(),
+		Aliases: []string{"copper", "coppor", "cooper ore"},
+	},
+	"Iron Ore": {
+		Name: "Iron Ore", Rarity: Common, Multiplier: 1.2, Color: Common.Color(),
+		Aliases: []string{"iron", "lron", "1ron"},
+	},
+	"Tin Ore": {
+		Name: "Tin Ore", Rarity: Uncommon, Multiplier: 1.3, Color: Uncommon.Color(),
+		Aliases: []string{"tin", "t1n", "tln"},
+	},
+	"Silver Ore": {
+		Name: "Silver Ore", Rarity: Uncommon, Multiplier: 1.4, Color: Uncommon.Color(),
+		Aliases: []string{"silver", "sliver", "si1ver"},
+	},
+	"Gold Ore": {
+		Name: "Gold Ore", Rarity: Uncommon, Multiplier: 1.5, Color: Uncommon.Color(),
+		Aliases: []string{"gold", "g0ld", "golo ore"},
+	},
+	"Topaz Ore": {
+		Name: "Topaz Ore", Rarity: Rare, Multiplier: 1.6, Color: Rare.Color(),
+		Aliases: []string{"topaz", "t0paz", "topez"},
+	},
+	"Emerald Ore": {
+		Name: "Emerald Ore", Rarity: Rare, Multiplier: 1.7, Color: Rare.Color(),
+		Aliases: []string{"emerald", "emera1d", "emerold"},
+	},
+	"Ruby Ore": {
+		Name: "Ruby Ore", Rarity: Rare, Multiplier: 1.8, Color: Rare.Color(),
+		Aliases: []string{"ruby", "rubv", "ruhy"},
+	},
+	"Rivalite Ore": {
+		Name: "Rivalite Ore", Rarity: Rare, Multiplier: 1.75, Color: Rare.Color(),
+		Aliases: []string{"rivalite", "riva1ite", "rivallte"},
+	},
+	"Eye Ore": {
+		Name: "Eye Ore", Rarity: Epic, Multiplier: 1.9, Color: Epic.Color(),
+		Aliases: []string{"eye ore", "eve ore", "eye 0re"},
+	},
+	"Magmaite Ore": {
+		Name: "Magmaite Ore", Rarity: Epic, Multiplier: 1.95, Color: Epic.Color(),
+		Aliases: []string{"magmaite", "magmalte", "magmite"},
+	},
+	"Sapphire Ore": {
+		Name: "Sapphire Ore", Rarity: Legendary, Multiplier: 2.0, Color: Legendary.Color(),
+		Aliases: []string{"sapphire", "saphire", "sapphlre"},
+	},
+	"Titanium Ore": {
+		Name: "Titanium Ore", Rarity: Legendary, Multiplier: 2.2, Color: Legendary.Color(),
+		Aliases: []string{"titanium", "tltanium", "titanlum"},
+	},
+	"Orichalcum Ore": {
+		Name: "Orichalcum Ore", Rarity: Legendary, Multiplier: 2.4, Color: Legendary.Color(),
+		Aliases: []string{"orichalcum", "orichalcurn", "orlchalcum"},
+	},
+	"Mythril Ore": {
+		Name: "Mythril Ore", Rarity: Mythical, Multiplier: 2.6, Color: Mythical.Color(),
+		Aliases: []string{"mythril", "mithril", "mythrll"},
+	},
+	"Adamantite Ore": {
+		Name: "Adamantite Ore", Rarity: Mythical, Multiplier: 2.8, Color: Mythical.Color(),
+		Aliases: []string{"adamantite", "adamantlte", "adamantine"},
+	},
+}
+
+var LegendaryMythic = OresAtLeast(Legendary)
+
+// matchNames is every ore name and alias in the order Match tries them.
+var matchNames = sortedNames()
+
+type matchName struct {
+	text string // lower case
+	ore  string
+}
+
+// sortedNames lists the names and aliases of Ores longest first, then
+// alphabetically, so Match doesn't depend on map order.
+func sortedNames() []matchName {
+	var names []matchName
+	for name, ore := range Ores {
+		for _, text := range ore.names() {
+			names = append(names, matchName{text: text, ore: name})
+		}
+	}
+	sort.Slice(names, func(i, j int) bool {
+		a, b := names[i], names[j]
+		if len(a.text) != len(b.text) {
+			return len(a.text) > len(b.text)
+		}
+		if a.text != b.text {
+			return a.text < b.text
+		}
+		return a.ore < b.ore
+	})
+	return names
+}
+
+// OresAtLeast returns the sorted names of ores of rarity min or higher.
+func OresAtLeast(min Rarity) []string {
+	var names []string
+	for name, ore := range Ores {
+		if ore.Rarity.AtLeast(min) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// minPartialMatch is the shortest name Match finds inside another word.
+// Shorter ones such as "tin" or "iron" turn up in unrelated words.
+const minPartialMatch = 6
+
+// Match returns the ore whose name or alias appears in text. A name found
+// as whole words beats one found inside another word, which only names of
+// minPartialMatch letters or more may be, for OCR that runs words
+// together; so "tin" doesn't match "tinted". Then longer names win so
+// "eye ore" isn't shadowed by a shorter alias elsewhere, and remaining
+// ties go alphabetically.
+func Match(text string) (Ore, bool) {
+	text = strings.ToLower(text)
+
+	partial := ""
+	for _, name := range matchNames {
+		switch {
+		case containsWord(text, name.text):
+			return Ores[name.ore], true
+		case partial == "" && len(name.text) >= minPartialMatch && strings.Contains(text, name.text):
+			partial = name.ore
+		}
+	}
+	if partial == "" {
+		return Ore{}, false
+	}
+	return Ores[partial], true
+}
+
+// containsWord reports whether word appears in text with no letter or
+// digit directly before or after it.
+func containsWord(text, word string) bool {
+	for start := 0; ; {
+		i := strings.Index(text[start:], word)
+		if i < 0 {
+			return false
+		}
+		i += start
+		end := i + len(word)
+		if (i == 0 || !isWordByte(text[i-1])) && (end == len(text) || !isWordByte(text[end])) {
+			return true
+		}
+		start = i + 1
+	}
+}
+
+func isWordByte(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c >= 0x80
+}
+
+func (o Ore) names() []string {
+	nameLower := strings.ToLower(o.Name)
+	names := []string{nameLower, strings.TrimSuffix(nameLower, " ore")}
+	for _, alias := range o.Aliases {
+		names = append(names, strings.ToLower(alias))
+	}
+	return names
+}
+
+// SellValue returns the total sell price for the given ore counts.
+func SellValue(counts map[string]int) int {
+	total := 0
+	for name, count := range counts {
+		total += Ores[name].SellPrice * count
+	}
+	return total
+}
+
+// LoadFile merges user-defined ores from a JSON array into Ores, replacing
+// built-in entries with the same name. A missing file is not an error.
+func LoadFile(path string) error {
+	raw, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var entries []json.RawMessage
+	if err := json.Unmarshal(raw, &entries); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+
+	loaded := make([]Ore, 0, len(entries))
+	for i, entry := range entries {
+		var ore Ore
+		if err := json.Unmarshal(entry, &ore); err != nil {
+			return fmt.Errorf("%s: ore #%d: %w", path, i+1, err)
+		}
+		if ore.Name == "" {
+			return fmt.Errorf("%s: ore #%d: missing name", path, i+1)
+		}
+		if !ore.Rarity.Valid() {
+			return fmt.Errorf("%s: ore %q: missing rarity", path, ore.Name)
+		}
+		if ore.Multiplier <= 0 {
+			return fmt.Errorf("%s: ore %q: multiplier must be positive", path, ore.Name)
+		}
+		ore.Color = ore.Rarity.Color()
+		loaded = append(loaded, ore)
+	}
+
+	for _, ore := range loaded {
+		Ores[ore.Name] = ore
+	}
+	LegendaryMythic = OresAtLeast(Legendary)
+	matchNames = sortedNames()
+	return nil
 }
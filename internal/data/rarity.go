@@ -0,0 +1,106 @@
+package data
+
+import (
+	"fmt"
+	"image/color"
+	"strings"
+)
+
+// Rarity is ordered from least to most rare so tiers can be compared.
+type Rarity int
+
+const (
+	Common Rarity = iota + 1
+	Uncommon
+	Rare
+	Epic
+	Legendary
+	Mythical
+)
+
+var rarityNames = map[Rarity]string{
+	Common:    "common",
+	Uncommon:  "uncommon",
+	Rare:      "rare",
+	Epic:      "epic",
+	Legendary: "legendary",
+	Mythical:  "mythical",
+}
+
+var rarityColors = map[Rarity]color.RGBA{
+	Common:    {R: 190, G: 190, B: 190, A: 255},
+	Uncommon:  {R: 85, G: 200, B: 85, A: 255},
+	Rare:      {R: 60, G: 130, B: 235, A: 255},
+	Epic:      {R: 165, G: 75, B: 225, A: 255},
+	Legendary: {R: 245, G: 180, B: 40, A: 255},
+	Mythical:  {R: 235, G: 55, B: 70, A: 255},
+}
+
+// Rarities lists every rarity from lowest to highest.
+func Rarities() []Rarity {
+	return []Rarity{Common, Uncommon, Rare, Epic, Legendary, Mythical}
+}
+
+func ParseRarity(s string) (Rarity, error) {
+	name := strings.ToLower(strings.TrimSpace(s))
+	if name == "mythic" {
+		return Mythical, nil
+	}
+	for r, n := range rarityNames {
+		if n == name {
+			return r, nil
+		}
+	}
+	names := make([]string, 0, len(rarityNames))
+	for _, r := range Rarities() {
+		names = append(names, r.String())
+	}
+	return 0, fmt.Errorf("unknown rarity %q (want one of %s)", s, strings.Join(names, ", "))
+}
+
+func (r Rarity) String() string {
+	if name, ok := rarityNames[r]; ok {
+		return name
+	}
+	if r == 0 {
+		return ""
+	}
+	return fmt.Sprintf("rarity(%d)", int(r))
+}
+
+func (r Rarity) Valid() bool {
+	_, ok := rarityNames[r]
+	return ok
+}
+
+// AtLeast reports whether r is as rare as min or rarer.
+func (r Rarity) AtLeast(min Rarity) bool {
+	return r >= min
+}
+
+// Color is the rarity color the game uses for item names.
+func (r Rarity) Color() color.RGBA {
+	return rarityColors[r]
+}
+
+func (r Rarity) MarshalText() ([]byte, error) {
+	if r != 0 && !r.Valid() {
+		return nil, fmt.Errorf("invalid rarity %d", int(r))
+	}
+	return []byte(r.String()), nil
+}
+
+// UnmarshalText accepts an empty string as "unset" so optional rarity
+// settings can be left blank.
+func (r *Rarity) UnmarshalText(text []byte) error {
+	if len(strings.TrimSpace(string(text))) == 0 {
+		*r = 0
+		return nil
+	}
+	parsed, err := ParseRarity(string(text))
+	if err != nil {
+		return err
+	}
+	*r = parsed
+	return nil
+}
@@ -0,0 +1,152 @@
+package events
+
+import (
+	"forger-companion/internal/data"
+	"sync"
+	"time"
+)
+
+// Kind identifies what happened. The values double as keys in the
+// webhook.events section of settings.json.
+type Kind string
+
+const (
+	MacroStarted   Kind = "macro_started"
+	MacroStopped   Kind = "macro_stopped"
+	MacroCrashed   Kind = "macro_crashed"
+	SellFailed     Kind = "sell_failed"
+	GameWindowLost Kind = "game_window_lost"
+	OreFound       Kind = "ore_found"
+	LevelUp        Kind = "level_up"
+	MoneyMilestone Kind = "money_milestone"
+	SessionReport  Kind = "session_report"
+)
+
+// Kinds that are only streamed to the GUI and web clients, never alerted.
+const (
+	ScanResult    Kind = "scan_result"
+	ForgeUI       Kind = "forge_ui"
+	CycleStarted  Kind = "cycle_started"
+	CycleFinished Kind = "cycle_finished"
+	StatsChanged  Kind = "stats_changed"
+	Error         Kind = "error"
+)
+
+// Kinds lists the kinds notifiers can be alerted about, in display order.
+var Kinds = []Kind{
+	MacroStarted, MacroStopped, MacroCrashed, SellFailed,
+	GameWindowLost, OreFound, LevelUp, MoneyMilestone, SessionReport,
+}
+
+// Event is something worth telling the user about. Only the fields that
+// apply to Kind are set.
+type Event struct {
+	Seq     uint64    `json:"seq"`
+	Kind    Kind      `json:"kind"`
+	Time    time.Time `json:"time"`
+	Message string    `json:"message"`
+	Cycle   int       `json:"cycle,omitempty"`
+
+	Ore    string      `json:"ore,omitempty"`    // ore_found
+	Rarity data.Rarity `json:"rarity,omitempty"` // ore_found
+	Count  int         `json:"count,omitempty"`  // ore_found: total now held
+	Level  int         `json:"level,omitempty"`  // level_up
+	Money  int         `json:"money,omitempty"`  // money_milestone
+	Error  string      `json:"error,omitempty"`  // macro_crashed, sell_failed, error
+
+	Seconds   float64 `json:"seconds,omitempty"`    // cycle_finished: how long it took
+	ForgeOpen bool    `json:"forge_open,omitempty"` // forge_ui
+	HasOres   bool    `json:"has_ores,omitempty"`   // forge_ui
+
+	// Data is the scan_result *calculator.Result or the stats_changed
+	// *ocr.Stats.
+	Data interface{} `json:"data,omitempty"`
+}
+
+// historySize is how many events the bus keeps for clients that
+// reconnect.
+const historySize = 500
+
+// Bus fans events out to subscribers. Handlers run synchronously on the
+// publishing goroutine, so they must not block.
+//
+// Every event gets the next sequence number, and the most recent ones are
+// kept so a client can catch up on what it missed with Since.
+type Bus struct {
+	mu       sync.RWMutex
+	handlers map[int]func(Event)
+	nextID   int
+	seq      uint64
+	history  []Event
+}
+
+func NewBus() *Bus {
+	return &Bus{handlers: make(map[int]func(Event))}
+}
+
+// Subscribe registers fn for every published event and returns a function
+// that removes it again.
+func (b *Bus) Subscribe(fn func(Event)) (unsubscribe func()) {
+	b.mu.Lock()
+	id := b.nextID
+	b.nextID++
+	b.handlers[id] = fn
+	b.mu.Unlock()
+
+	return func() {
+		b.mu.Lock()
+		delete(b.handlers, id)
+		b.mu.Unlock()
+	}
+}
+
+// Publish numbers e, stamps it with the current time if unset and hands it
+// to every subscriber.
+func (b *Bus) Publish(e Event) {
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+
+	b.mu.Lock()
+	b.seq++
+	e.Seq = b.seq
+	if len(b.history) == historySize {
+		b.history = append(b.history[:0], b.history[1:]...)
+	}
+	b.history = append(b.history, e)
+	handlers := make([]func(Event), 0, len(b.handlers))
+	for _, fn := range b.handlers {
+		handlers = append(handlers, fn)
+	}
+	b.mu.Unlock()
+
+	for _, fn := range handlers {
+		fn(e)
+	}
+}
+
+// Seq is the number of the last event published.
+func (b *Bus) Seq() uint64 {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.seq
+}
+
+// Since returns the kept events numbered after seq. complete is false when
+// some of them are no longer kept, or seq is from before a restart; the
+// caller should then reload its state instead of relying on the events.
+func (b *Bus) Since(seq uint64) (missed []Event, complete bool) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	if seq > b.seq {
+		return append([]Event(nil), b.history...), false
+	}
+	complete = len(b.history) == 0 || b.history[0].Seq <= seq+1
+	for _, e := range b.history {
+		if e.Seq > seq {
+			missed = append(missed, e)
+		}
+	}
+	return missed, complete
+}
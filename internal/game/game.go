@@ -0,0 +1,30 @@
+// Package game finds the Roblox client on screen.
+package game
+
+import "image"
+
+// Process is the Roblox client process name.
+const Process = "RobloxPlayerBeta"
+
+// Running reports whether the client is running. The error is set when
+// the process list can't be read, in which case running is unknown.
+func Running() (bool, error) {
+	ids, err := findIds(Process)
+	if err != nil {
+		return false, err
+	}
+	return len(ids) > 0, nil
+}
+
+// Window returns the client area of the game window in screen coordinates.
+func Window() (image.Rectangle, bool) {
+	ids, err := findIds(Process)
+	if err != nil || len(ids) == 0 {
+		return image.Rectangle{}, false
+	}
+	x, y, w, h := clientRect(ids[0])
+	if w <= 0 || h <= 0 {
+		return image.Rectangle{}, false
+	}
+	return image.Rect(x, y, x+w, y+h), true
+}
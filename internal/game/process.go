@@ -0,0 +1,13 @@
+//go:build cgo
+
+package game
+
+import "github.com/go-vgo/robotgo"
+
+func findIds(name string) ([]int, error) {
+	return robotgo.FindIds(name)
+}
+
+func clientRect(pid int) (x, y, w, h int) {
+	return robotgo.GetClient(pid)
+}
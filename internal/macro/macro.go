@@ -1,82 +1,293 @@
 package macro
 
 import (
+	"errors"
+	"fmt"
 	"forger-companion/internal/config"
+	"forger-companion/internal/events"
+	"forger-companion/internal/game"
+	"forger-companion/internal/metrics"
 	"forger-companion/internal/ocr"
+	"forger-companion/internal/stats"
 	"forger-companion/internal/webhook"
 	"log"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/go-vgo/robotgo"
 )
 
+// States reported by Macro.State.
+const (
+	StateStopped = "stopped"
+	StateRunning = "running"
+	StatePaused  = "paused"
+)
+
+var (
+	cyclesTotal   = metrics.NewCounter("forger_macro_cycles_total", "Macro cycles completed.")
+	cycleDuration = metrics.NewHistogram("forger_macro_cycle_duration_seconds", "Time taken by each macro cycle.",
+		[]float64{10, 20, 30, 45, 60, 90, 120, 180, 300, 600, 1200, 3600})
+	sellFailures = metrics.NewCounter("forger_macro_sell_failures_total", "Auto-sell attempts that failed.")
+	money        = metrics.NewGauge("forger_money", "Money read from the last stats scan.")
+	level        = metrics.NewGauge("forger_level", "Level read from the last stats scan.")
+)
+
+// statsInterval is the least time between stats scans, other than the
+// one after each sell.
+const statsInterval = time.Minute
+
+// ErrStopping is returned by Start while the last session is still
+// finishing up.
+var ErrStopping = errors.New("the last session is still being saved, try again in a moment")
+
 type Macro struct {
-	cfg            *config.Config
-	running        bool
-	stopChan       chan bool
+	cfg            atomic.Pointer[config.Config] // replaced, never changed, on reload
 	webhookManager *webhook.Manager
 	scanner        *ocr.Scanner
+	bus            *events.Bus
+	tracker        *stats.Tracker
+	windowLost     bool      // used by the run goroutine only
+	lastStats      time.Time // when stats were last read, by run
+	lastMoney      int       // money then, or 0 if unknown
+
+	mu         sync.Mutex
+	running    bool
+	paused     bool
+	stop       chan struct{} // closed by Stop
+	done       chan struct{} // closed when run returns
+	cycle      int
+	session    *stats.Session
+	lastReport *stats.Report
 }
 
-func New(cfg *config.Config, scanner *ocr.Scanner) *Macro {
-	return &Macro{
-		cfg:            cfg,
-		stopChan:       make(chan bool),
+func New(cfg *config.Config, scanner *ocr.Scanner, bus *events.Bus) *Macro {
+	m := &Macro{
 		webhookManager: webhook.NewManager(cfg),
 		scanner:        scanner,
+		bus:            bus,
+		tracker:        stats.NewTracker(bus, cfg.Webhook.MoneyMilestone),
 	}
+	m.cfg.Store(cfg)
+	bus.Subscribe(m.webhookManager.HandleEvent)
+	return m
+}
+
+// settings returns the settings in effect; take them once per step so a
+// reload midway doesn't mix old and new values.
+func (m *Macro) settings() *config.Config {
+	return m.cfg.Load()
 }
 
 func (m *Macro) IsRunning() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 	return m.running
 }
 
+// State is "stopped", "running" or "paused".
+func (m *Macro) State() string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	switch {
+	case !m.running:
+		return StateStopped
+	case m.paused:
+		return StatePaused
+	}
+	return StateRunning
+}
+
+// Cycle is the number of the cycle in progress, or the last one run.
+func (m *Macro) Cycle() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.cycle
+}
+
+// Pause holds the macro at the start of the next cycle until Resume.
+func (m *Macro) Pause() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.running && !m.paused {
+		m.paused = true
+		log.Println("[Macro] Pausing after this cycle")
+	}
+}
+
+func (m *Macro) Resume() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.paused {
+		m.paused = false
+		log.Println("[Macro] Resumed")
+	}
+}
+
+// Session summarizes the session in progress, or returns nil when the
+// macro isn't running.
+func (m *Macro) Session() *stats.Report {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.session == nil {
+		return nil
+	}
+	return m.session.Snapshot()
+}
+
+// WebhookStatus reports queued, delivered and dead-lettered updates.
+func (m *Macro) WebhookStatus() webhook.DeliveryStatus {
+	return m.webhookManager.Status()
+}
+
+// ConfigChanged is called by the config watcher after settings reload.
+func (m *Macro) ConfigChanged(cfg *config.Config) {
+	m.cfg.Store(cfg)
+	m.webhookManager.ConfigChanged(cfg)
+	m.tracker.SetMilestone(cfg.Webhook.MoneyMilestone)
+	if m.IsRunning() {
+		log.Println("[Macro] Settings reloaded, changes apply from the next cycle")
+	}
+}
+
 func (m *Macro) Start() error {
+	m.mu.Lock()
 	if m.running {
+		m.mu.Unlock()
 		return nil
 	}
-
-	buttons := m.cfg.MacroButtons
+	if m.done != nil {
+		select {
+		case <-m.done:
+		default:
+			m.mu.Unlock()
+			return ErrStopping
+		}
+	}
+	buttons := m.settings().MacroButtons
 	if buttons["break_position"] == nil || buttons["inventory"] == nil {
+		m.mu.Unlock()
 		log.Println("[Macro] Not all buttons configured")
-		return nil
+		return fmt.Errorf("break position and inventory buttons must be configured")
 	}
-
+	stop, done := make(chan struct{}), make(chan struct{})
+	m.stop, m.done = stop, done
 	m.running = true
-	go m.run()
+	m.paused = false
+	m.mu.Unlock()
+
+	m.windowLost = false
+	m.lastStats, m.lastMoney = time.Time{}, 0
+	m.tracker.Reset()
+	m.webhookManager.StartSession()
+	m.bus.Publish(events.Event{Kind: events.MacroStarted, Message: "Macro started"})
+	go m.run(stop, done)
 	return nil
 }
 
+// LastReport returns the summary of the most recent session, or nil if the
+// macro hasn't stopped yet.
+func (m *Macro) LastReport() *stats.Report {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.lastReport
+}
+
 func (m *Macro) Stop() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 	if !m.running {
 		return
 	}
 	m.running = false
-	m.stopChan <- true
+	m.paused = false
+	close(m.stop)
+}
+
+// Close stops the macro, waits for its session report and stops webhook
+// delivery. The macro can't be used afterwards.
+func (m *Macro) Close() {
+	m.Stop()
+	m.Wait()
+	m.webhookManager.Close()
+}
+
+// Wait blocks until the macro has stopped and its session report is
+// saved.
+func (m *Macro) Wait() {
+	m.mu.Lock()
+	done := m.done
+	m.mu.Unlock()
+	if done != nil {
+		<-done
+	}
 }
 
-func (m *Macro) run() {
+// run is one session. Start won't begin another until done is closed, so
+// only one runs at a time.
+func (m *Macro) run(stop, done chan struct{}) {
+	cycle := 1
+	session := stats.NewSession()
+	m.mu.Lock()
+	m.session = session
+	m.mu.Unlock()
+
+	defer close(done)
 	defer func() {
-		m.running = false
+		m.mu.Lock()
+		m.running = false // in case it ended without Stop
+		m.paused = false
+		m.mu.Unlock()
 		robotgo.Toggle("left", "up")
-	}()
 
-	cycle := 1
-	holdDuration := 5 * time.Minute
-	if duration, ok := m.cfg.MacroSettings["hold_duration"].(float64); ok {
-		holdDuration = time.Duration(duration) * time.Minute
-	}
+		r := recover()
+		var crash error
+		if r != nil {
+			crash = fmt.Errorf("crashed: %v", r)
+		}
+		m.finishSession(session, crash)
 
-	autoSell := true
-	if sell, ok := m.cfg.MacroSettings["auto_sell"].(bool); ok {
-		autoSell = sell
-	}
+		if r != nil {
+			log.Printf("[Macro] Crashed: %v", r)
+			m.bus.Publish(events.Event{
+				Kind:    events.MacroCrashed,
+				Message: "Macro stopped unexpectedly",
+				Cycle:   cycle,
+				Error:   fmt.Sprint(r),
+			})
+			return
+		}
+		m.bus.Publish(events.Event{
+			Kind:    events.MacroStopped,
+			Message: fmt.Sprintf("Macro stopped after %d cycles", cycle-1),
+			Cycle:   cycle - 1,
+		})
+	}()
+
+	for {
+		if !m.waitWhilePaused(stop) {
+			return
+		}
+		m.mu.Lock()
+		m.cycle = cycle
+		m.mu.Unlock()
 
-	for m.running {
 		log.Printf("[Macro] Starting cycle %d", cycle)
+		cycleStart := time.Now()
+		m.bus.Publish(events.Event{
+			Kind:    events.CycleStarted,
+			Message: fmt.Sprintf("Cycle %d started", cycle),
+			Cycle:   cycle,
+		})
+
+		// Read settings each cycle so reloaded config applies without a restart
+		cfg := m.settings()
+		holdDuration := cfg.MacroSettings.HoldDuration.Duration()
+		autoSell := cfg.MacroSettings.AutoSell
 
 		// Hold M1 at break position
-		breakPos := m.cfg.MacroButtons["break_position"]
+		breakPos := cfg.MacroButtons["break_position"]
 		if breakPos != nil && breakPos.X != nil && breakPos.Y != nil {
 			log.Println("[Macro] Moving to break position and holding M1...")
 			robotgo.Move(*breakPos.X, *breakPos.Y)
@@ -85,7 +296,7 @@ func (m *Macro) run() {
 			// Hold for duration
 			select {
 			case <-time.After(holdDuration):
-			case <-m.stopChan:
+			case <-stop:
 				return
 			}
 
@@ -93,88 +304,228 @@ func (m *Macro) run() {
 			time.Sleep(500 * time.Millisecond)
 		}
 
+		m.checkGameWindow(cycle)
+
 		// Auto-sell if enabled
+		var sellErr error
+		if autoSell {
+			sellErr = m.performSell()
+		}
+
+		// Scan stats after each sell, to check it, and otherwise at most
+		// every statsInterval: without a stats region it reads the whole
+		// screen
+		var scanned *ocr.Stats
+		if m.webhookManager.TrackStats() && (autoSell || time.Since(m.lastStats) >= statsInterval) {
+			moneyBefore := m.lastMoney
+			if s, err := m.scanStats(cfg); err == nil {
+				scanned = s
+				m.tracker.Observe(cycle, scanned)
+				session.Observe(scanned)
+				money.Set(float64(scanned.Money))
+				level.Set(float64(scanned.Level))
+				if autoSell && sellErr == nil {
+					sellErr = m.verifySell(cfg, moneyBefore, scanned)
+				}
+			} else {
+				m.fail(session, cycle, fmt.Errorf("stats scan: %w", err))
+			}
+		}
+
 		if autoSell {
-			if err := m.performSell(); err != nil {
-				log.Printf("[Macro] Sell error: %v", err)
+			session.SellDone(sellErr)
+			if sellErr != nil {
+				sellFailures.Inc()
+				log.Printf("[Macro] Sell error: %v", sellErr)
+				m.bus.Publish(events.Event{
+					Kind:    events.SellFailed,
+					Message: "Selling failed",
+					Cycle:   cycle,
+					Error:   sellErr.Error(),
+				})
 			}
 		}
 
 		// Send webhook update if needed
 		if m.webhookManager.ShouldSendUpdate(cycle) {
 			log.Println("[Macro] Sending progress update...")
-			
-			var stats *ocr.Stats
-			if m.webhookManager.TrackStats() {
-				if s, err := m.scanner.ScanForStats(nil); err == nil {
-					stats = s
-				}
-			}
-			
-			if err := m.webhookManager.SendUpdate(cycle, stats); err != nil {
+			if err := m.webhookManager.SendUpdate(cycle, scanned); err != nil {
 				log.Printf("[Macro] Webhook error: %v", err)
+				m.fail(session, cycle, fmt.Errorf("webhook: %w", err))
 			}
 		}
 
+		took := time.Since(cycleStart)
+		session.CycleDone(took)
+		cyclesTotal.Inc()
+		cycleDuration.Observe(took.Seconds())
+		m.bus.Publish(events.Event{
+			Kind:    events.CycleFinished,
+			Message: fmt.Sprintf("Cycle %d finished in %v", cycle, took.Round(time.Second)),
+			Cycle:   cycle,
+			Seconds: took.Seconds(),
+		})
 		cycle++
 		time.Sleep(500 * time.Millisecond)
 
 		select {
-		case <-m.stopChan:
+		case <-stop:
 			return
 		default:
 		}
 	}
 }
 
-func (m *Macro) performSell() error {
-	log.Println("[Macro] Opening inventory...")
+// scanStats reads the stats from the "stats" region, or the whole screen
+// if none is set.
+func (m *Macro) scanStats(cfg *config.Config) (*ocr.Stats, error) {
+	s, err := m.scanner.ScanForStats(cfg.Regions["stats"])
+	if err != nil {
+		return nil, err
+	}
+	m.lastStats = time.Now()
+	if s.Money > 0 {
+		m.lastMoney = s.Money
+	}
+	return s, nil
+}
 
-	// Open inventory (E key or click)
-	invButton := m.cfg.MacroButtons["inventory"]
-	if invButton != nil {
-		if invButton.Key != nil {
-			robotgo.KeyTap(*invButton.Key)
-		} else if invButton.X != nil && invButton.Y != nil {
-			robotgo.Click(*invButton.X, *invButton.Y)
+// verifySell checks that money went up from before, the last money read
+// before selling. A misread is ruled out by reading once more.
+func (m *Macro) verifySell(cfg *config.Config, before int, after *ocr.Stats) error {
+	if before == 0 || after.Money > before {
+		return nil // nothing to compare with, or it worked
+	}
+	time.Sleep(time.Second)
+	if again, err := m.scanStats(cfg); err == nil && again.Money > before {
+		return nil
+	}
+	return fmt.Errorf("sell not confirmed: money stayed at $%d", before)
+}
+
+// fail records a non-fatal error in the session and publishes it.
+func (m *Macro) fail(session *stats.Session, cycle int, err error) {
+	session.Error(err)
+	m.bus.Publish(events.Event{
+		Kind:    events.Error,
+		Message: "Macro error",
+		Cycle:   cycle,
+		Error:   err.Error(),
+	})
+}
+
+// waitWhilePaused blocks while the macro is paused. It returns false if
+// the macro was stopped meanwhile.
+func (m *Macro) waitWhilePaused(stop chan struct{}) bool {
+	for m.State() == StatePaused {
+		select {
+		case <-stop:
+			return false
+		case <-time.After(200 * time.Millisecond):
 		}
 	}
-	time.Sleep(500 * time.Millisecond)
+	select {
+	case <-stop:
+		return false
+	default:
+		return true
+	}
+}
+
+// finishSession saves the session report, sends it to the notifiers and
+// keeps it for the GUI.
+func (m *Macro) finishSession(session *stats.Session, crash error) {
+	report := session.Finish(crash)
 
-	// Click Sell tab
-	if sellTab := m.cfg.MacroButtons["sell_tab"]; sellTab != nil && sellTab.X != nil && sellTab.Y != nil {
-		log.Println("[Macro] Clicking Sell tab...")
-		robotgo.Click(*sellTab.X, *sellTab.Y)
-		time.Sleep(300 * time.Millisecond)
+	if path, err := report.Save(config.SessionsDir()); err != nil {
+		log.Printf("[Macro] Can't save session report: %v", err)
+	} else {
+		log.Printf("[Macro] Session report saved to %s", path)
 	}
+	if err := m.webhookManager.SendReport(report); err != nil {
+		log.Printf("[Macro] Webhook error: %v", err)
+	}
+
+	m.mu.Lock()
+	m.session = nil
+	m.lastReport = report
+	m.mu.Unlock()
+}
 
-	// Click Select All
-	if selectAll := m.cfg.MacroButtons["select_all"]; selectAll != nil && selectAll.X != nil && selectAll.Y != nil {
-		log.Println("[Macro] Clicking Select All...")
-		robotgo.Click(*selectAll.X, *selectAll.Y)
-		time.Sleep(300 * time.Millisecond)
+// checkGameWindow publishes game_window_lost once when the Roblox client
+// disappears, and again only after it has come back.
+func (m *Macro) checkGameWindow(cycle int) {
+	running, err := game.Running()
+	if err != nil {
+		return
+	}
+	if running {
+		m.windowLost = false
+		return
 	}
+	if !m.windowLost {
+		m.windowLost = true
+		log.Println("[Macro] Game window not found")
+		m.bus.Publish(events.Event{
+			Kind:    events.GameWindowLost,
+			Message: "Roblox is no longer running",
+			Cycle:   cycle,
+		})
+	}
+}
+
+// sellSteps are the buttons performSell clicks after opening the inventory,
+// in order; config.SellButtons lists the same names.
+var sellSteps = []struct {
+	name, label string
+	wait        time.Duration
+}{
+	{"sell_tab", "Sell tab", 300 * time.Millisecond},
+	{"select_all", "Select All", 300 * time.Millisecond},
+	{"accept", "Accept", 300 * time.Millisecond},
+	{"yes_confirm", "Yes", 300 * time.Millisecond},
+	{"close_menu", "close menu", 500 * time.Millisecond},
+}
 
-	// Click Accept
-	if accept := m.cfg.MacroButtons["accept"]; accept != nil && accept.X != nil && accept.Y != nil {
-		log.Println("[Macro] Clicking Accept...")
-		robotgo.Click(*accept.X, *accept.Y)
-		time.Sleep(300 * time.Millisecond)
+// performSell opens the inventory and clicks through the sell screens. It
+// fails if a button isn't set; run checks afterwards that money went up.
+func (m *Macro) performSell() error {
+	buttons := m.settings().MacroButtons
+	for _, step := range sellSteps {
+		if b := buttons[step.name]; b == nil || !b.HasPosition() {
+			return fmt.Errorf("%s button not configured", step.name)
+		}
 	}
+	log.Println("[Macro] Opening inventory...")
 
-	// Click Yes confirm
-	if yesConfirm := m.cfg.MacroButtons["yes_confirm"]; yesConfirm != nil && yesConfirm.X != nil && yesConfirm.Y != nil {
-		log.Println("[Macro] Clicking Yes...")
-		robotgo.Click(*yesConfirm.X, *yesConfirm.Y)
-		time.Sleep(300 * time.Millisecond)
+	// Open inventory (E key or click)
+	invButton := buttons["inventory"]
+	switch {
+	case invButton == nil:
+		return fmt.Errorf("inventory button not configured")
+	case invButton.Key != nil:
+		if err := robotgo.KeyTap(*invButton.Key); err != nil {
+			return fmt.Errorf("open inventory: %w", err)
+		}
+	case invButton.X != nil && invButton.Y != nil:
+		clickAt(*invButton.X, *invButton.Y)
 	}
+	time.Sleep(500 * time.Millisecond)
 
-	// Close menu
-	if closeMenu := m.cfg.MacroButtons["close_menu"]; closeMenu != nil && closeMenu.X != nil && closeMenu.Y != nil {
-		log.Println("[Macro] Closing menu...")
-		robotgo.Click(*closeMenu.X, *closeMenu.Y)
-		time.Sleep(500 * time.Millisecond)
+	// Click through the sell screens
+	for _, step := range sellSteps {
+		b := buttons[step.name]
+		log.Printf("[Macro] Clicking %s...", step.label)
+		clickAt(*b.X, *b.Y)
+		time.Sleep(step.wait)
 	}
 
 	return nil
 }
+
+// clickAt left-clicks the screen at x, y. robotgo.Click takes the button
+// and whether to double-click, not a position.
+func clickAt(x, y int) {
+	robotgo.Move(x, y)
+	robotgo.Click("left")
+}
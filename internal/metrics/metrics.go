@@ -0,0 +1,221 @@
+// Package metrics keeps counters, gauges and histograms and writes them in
+// the Prometheus text exposition format. Packages declare their series as
+// package variables; label values are passed on each update.
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+var registry = struct {
+	sync.Mutex
+	families map[string]*family
+}{families: make(map[string]*family)}
+
+type family struct {
+	name    string
+	help    string
+	kind    string // "counter", "gauge" or "histogram"
+	labels  []string
+	buckets []float64 // histogram upper bounds, ascending
+
+	mu     sync.Mutex
+	series map[string]*series // keyed by label values joined with \xff
+}
+
+type series struct {
+	labels []string
+	value  float64  // counter and gauge
+	counts []uint64 // histogram, one per bucket
+	sum    float64
+	count  uint64
+}
+
+func register(name, help, kind string, labels []string, buckets []float64) *family {
+	f := &family{
+		name:    name,
+		help:    help,
+		kind:    kind,
+		labels:  labels,
+		buckets: buckets,
+		series:  make(map[string]*series),
+	}
+	if len(labels) == 0 {
+		f.get(nil) // report unlabelled series from the start
+	}
+
+	registry.Lock()
+	defer registry.Unlock()
+	if _, ok := registry.families[name]; ok {
+		panic("metrics: " + name + " registered twice")
+	}
+	registry.families[name] = f
+	return f
+}
+
+// get returns the series for the label values, creating it if needed. The
+// caller holds f.mu, except during register.
+func (f *family) get(values []string) *series {
+	if len(values) != len(f.labels) {
+		panic(fmt.Sprintf("metrics: %s wants labels %v, got %v", f.name, f.labels, values))
+	}
+	key := strings.Join(values, "\xff")
+	s, ok := f.series[key]
+	if !ok {
+		s = &series{labels: append([]string(nil), values...)}
+		if f.kind == "histogram" {
+			s.counts = make([]uint64, len(f.buckets))
+		}
+		f.series[key] = s
+	}
+	return s
+}
+
+// Counter only goes up.
+type Counter struct{ f *family }
+
+func NewCounter(name, help string, labels ...string) *Counter {
+	return &Counter{register(name, help, "counter", labels, nil)}
+}
+
+func (c *Counter) Inc(labels ...string) { c.Add(1, labels...) }
+
+func (c *Counter) Add(v float64, labels ...string) {
+	c.f.mu.Lock()
+	defer c.f.mu.Unlock()
+	c.f.get(labels).value += v
+}
+
+// Gauge is a value that can go up and down.
+type Gauge struct{ f *family }
+
+func NewGauge(name, help string, labels ...string) *Gauge {
+	return &Gauge{register(name, help, "gauge", labels, nil)}
+}
+
+func (g *Gauge) Set(v float64, labels ...string) {
+	g.f.mu.Lock()
+	defer g.f.mu.Unlock()
+	g.f.get(labels).value = v
+}
+
+// Histogram counts observations into buckets.
+type Histogram struct{ f *family }
+
+// NewHistogram creates a histogram with the given bucket upper bounds; a
+// +Inf bucket is implied.
+func NewHistogram(name, help string, buckets []float64, labels ...string) *Histogram {
+	buckets = append([]float64(nil), buckets...)
+	sort.Float64s(buckets)
+	return &Histogram{register(name, help, "histogram", labels, buckets)}
+}
+
+func (h *Histogram) Observe(v float64, labels ...string) {
+	h.f.mu.Lock()
+	defer h.f.mu.Unlock()
+	s := h.f.get(labels)
+	for i, le := range h.f.buckets {
+		if v <= le {
+			s.counts[i]++
+		}
+	}
+	s.sum += v
+	s.count++
+}
+
+// Write writes every series in the text exposition format.
+func Write(w io.Writer) error {
+	registry.Lock()
+	families := make([]*family, 0, len(registry.families))
+	for _, f := range registry.families {
+		families = append(families, f)
+	}
+	registry.Unlock()
+	sort.Slice(families, func(i, j int) bool { return families[i].name < families[j].name })
+
+	out := bufio.NewWriter(w)
+	for _, f := range families {
+		f.write(out)
+	}
+	return out.Flush()
+}
+
+// Handler serves Write at a /metrics endpoint.
+func Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		Write(w)
+	})
+}
+
+func (f *family) write(w *bufio.Writer) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	fmt.Fprintf(w, "# HELP %s %s\n", f.name, escape(f.help, false))
+	fmt.Fprintf(w, "# TYPE %s %s\n", f.name, f.kind)
+
+	keys := make([]string, 0, len(f.series))
+	for key := range f.series {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		s := f.series[key]
+		if f.kind != "histogram" {
+			fmt.Fprintf(w, "%s%s %s\n", f.name, f.labelSet(s.labels, ""), formatFloat(s.value))
+			continue
+		}
+		for i, le := range f.buckets {
+			fmt.Fprintf(w, "%s_bucket%s %d\n", f.name, f.labelSet(s.labels, formatFloat(le)), s.counts[i])
+		}
+		fmt.Fprintf(w, "%s_bucket%s %d\n", f.name, f.labelSet(s.labels, "+Inf"), s.count)
+		fmt.Fprintf(w, "%s_sum%s %s\n", f.name, f.labelSet(s.labels, ""), formatFloat(s.sum))
+		fmt.Fprintf(w, "%s_count%s %d\n", f.name, f.labelSet(s.labels, ""), s.count)
+	}
+}
+
+// labelSet renders {name="value",...}, adding le for histogram buckets.
+func (f *family) labelSet(values []string, le string) string {
+	pairs := make([]string, 0, len(values)+1)
+	for i, v := range values {
+		pairs = append(pairs, fmt.Sprintf("%s=\"%s\"", f.labels[i], escape(v, true)))
+	}
+	if le != "" {
+		pairs = append(pairs, fmt.Sprintf("le=\"%s\"", le))
+	}
+	if len(pairs) == 0 {
+		return ""
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+func escape(s string, quotes bool) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, "\n", `\n`)
+	if quotes {
+		s = strings.ReplaceAll(s, `"`, `\"`)
+	}
+	return s
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
@@ -0,0 +1,207 @@
+// Package msgtemplate renders user-defined notification messages with Go
+// text/template.
+package msgtemplate
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"forger-companion/internal/data"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+)
+
+// Template overrides parts of a notification message. Every string is a
+// text/template executed against Data; empty parts keep the default.
+type Template struct {
+	Title       string  `json:"title,omitempty"`
+	Description string  `json:"description,omitempty"`
+	Color       string  `json:"color,omitempty"` // decimal or #rrggbb
+	Footer      string  `json:"footer,omitempty"`
+	Fields      []Field `json:"fields,omitempty"`
+}
+
+type Field struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Inline bool   `json:"inline,omitempty"`
+}
+
+// Data is what templates can refer to.
+type Data struct {
+	Event    string    // "progress" or an alert kind such as "macro_crashed"
+	Notifier string    // name of the notifier being rendered for
+	Time     time.Time // when the event happened
+	Cycle    int
+	Message  string // alert text, e.g. "Found mythical Mythril Ore (now 3)"
+	Error    string
+
+	Session    Session
+	Stats      *Stats // nil unless stats tracking is on
+	SinceLast  *Delta // nil on the first update of a session
+	SinceStart *Delta
+	Ore        *data.Ore // ore_found
+	Ores       map[string]data.Ore
+}
+
+type Session struct {
+	Start    time.Time
+	Duration time.Duration
+	Cycles   int
+}
+
+type Stats struct {
+	Ores      map[string]int
+	Level     int
+	Money     int
+	SellValue int // 0 unless ores.json gives sell prices
+}
+
+// Delta is the change over a period of the session.
+type Delta struct {
+	Ores    map[string]int
+	Level   int
+	Money   int
+	Over    time.Duration
+	Summary string // "+3 Mythril, +$1.2M, +2 levels"
+	Rates   string // "$600.0K/h, 2.0 ores/h"
+}
+
+// Funcs are available in every template.
+var Funcs = template.FuncMap{
+	"json": func(v interface{}) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+	"money": Money,
+	"short": func(name string) string { return strings.TrimSuffix(name, " Ore") },
+	"round": func(d time.Duration) time.Duration { return d.Round(time.Second) },
+	"upper": strings.ToUpper,
+	"lower": strings.ToLower,
+	"rarity": func(name string) string {
+		return data.Ores[name].Rarity.String()
+	},
+}
+
+// Rendered is a template's output. Empty strings and a zero Color mean
+// "keep the default".
+type Rendered struct {
+	Title       string
+	Description string
+	Color       int
+	Footer      string
+	Fields      []Field
+}
+
+// Parse compiles text with Funcs.
+func Parse(name, text string) (*template.Template, error) {
+	return template.New(name).Funcs(Funcs).Option("missingkey=error").Parse(text)
+}
+
+func (t Template) IsZero() bool {
+	return t.Title == "" && t.Description == "" && t.Color == "" && t.Footer == "" && len(t.Fields) == 0
+}
+
+// Render executes every part of t against d.
+func (t Template) Render(d *Data) (*Rendered, error) {
+	var r Rendered
+	var err error
+	exec := func(part, text string) string {
+		if err != nil || text == "" {
+			return ""
+		}
+		var out string
+		out, err = execute(part, text, d)
+		return out
+	}
+
+	r.Title = exec("title", t.Title)
+	r.Description = exec("description", t.Description)
+	r.Footer = exec("footer", t.Footer)
+	if color := strings.TrimSpace(exec("color", t.Color)); color != "" && err == nil {
+		r.Color, err = parseColor(color)
+	}
+	for i, f := range t.Fields {
+		name := exec(fmt.Sprintf("fields[%d].name", i), f.Name)
+		value := exec(fmt.Sprintf("fields[%d].value", i), f.Value)
+		if strings.TrimSpace(value) == "" {
+			continue // let templates drop fields that don't apply
+		}
+		r.Fields = append(r.Fields, Field{Name: name, Value: value, Inline: f.Inline})
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// Validate parses t and renders it against sample data for event, once
+// with every optional part filled in and once with none, so typos in field
+// names and missing {{with}} guards are caught when settings load.
+func (t Template) Validate(event string) error {
+	if _, err := t.Render(Sample(event)); err != nil {
+		return err
+	}
+	if _, err := t.Render(SparseSample(event)); err != nil {
+		return fmt.Errorf("without optional data (stats tracking off, first update): %w", err)
+	}
+	return nil
+}
+
+// parsed holds every template text compiled so far, keyed by part and
+// text. Settings are replaced rather than changed, so a text compiled once
+// is reused for every message sent with it.
+var parsed sync.Map
+
+func execute(part, text string, d *Data) (string, error) {
+	key := part + "\x00" + text
+	cached, ok := parsed.Load(key)
+	if !ok {
+		tmpl, err := Parse(part, text)
+		if err != nil {
+			return "", err
+		}
+		cached, _ = parsed.LoadOrStore(key, tmpl)
+	}
+	tmpl := cached.(*template.Template)
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, d); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+func parseColor(s string) (int, error) {
+	if hex, ok := strings.CutPrefix(s, "#"); ok {
+		n, err := strconv.ParseInt(hex, 16, 32)
+		if err != nil || len(hex) != 6 {
+			return 0, fmt.Errorf("color: %q is not #rrggbb", s)
+		}
+		return int(n), nil
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 0 || n > 0xFFFFFF {
+		return 0, fmt.Errorf("color: %q is not a color", s)
+	}
+	return n, nil
+}
+
+// Money shortens large amounts: $950, $12.5K, $1.2M, $3.4B.
+func Money(n int) string {
+	sign := ""
+	if n < 0 {
+		sign, n = "-", -n
+	}
+	switch {
+	case n >= 1e9:
+		return fmt.Sprintf("%s$%.1fB", sign, float64(n)/1e9)
+	case n >= 1e6:
+		return fmt.Sprintf("%s$%.1fM", sign, float64(n)/1e6)
+	case n >= 1e3:
+		return fmt.Sprintf("%s$%.1fK", sign, float64(n)/1e3)
+	}
+	return fmt.Sprintf("%s$%d", sign, n)
+}
@@ -0,0 +1,63 @@
+package msgtemplate
+
+import (
+	"forger-companion/internal/data"
+	"time"
+)
+
+// Sample returns made-up data for previewing and validating templates.
+// Every optional part is filled in so templates can be checked fully.
+func Sample(event string) *Data {
+	now := time.Now()
+	mythril := data.Ores["Mythril Ore"]
+
+	return &Data{
+		Event:    event,
+		Notifier: "preview",
+		Time:     now,
+		Cycle:    42,
+		Message:  "Found mythical Mythril Ore (now 3)",
+		Error:    "inventory button not configured",
+		Session: Session{
+			Start:    now.Add(-2 * time.Hour),
+			Duration: 2 * time.Hour,
+			Cycles:   42,
+		},
+		Stats: &Stats{
+			Ores:      map[string]int{"Mythril Ore": 3, "Sapphire Ore": 5},
+			Level:     57,
+			Money:     1730000,
+			SellValue: 11250,
+		},
+		SinceLast: &Delta{
+			Ores:    map[string]int{"Mythril Ore": 1},
+			Money:   240000,
+			Over:    10 * time.Minute,
+			Summary: "+1 Mythril, +$240.0K",
+		},
+		SinceStart: &Delta{
+			Ores:    map[string]int{"Mythril Ore": 3, "Sapphire Ore": 2},
+			Level:   2,
+			Money:   1200000,
+			Over:    2 * time.Hour,
+			Summary: "+3 Mythril, +2 Sapphire, +$1.2M, +2 levels",
+			Rates:   "$600.0K/h, 2.5 ores/h, 1.0 levels/h",
+		},
+		Ore:  &mythril,
+		Ores: data.Ores,
+	}
+}
+
+// SparseSample is Sample with every optional part left out, as when stats
+// tracking is off or on the first update of a session. Only ore_found
+// events carry an Ore.
+func SparseSample(event string) *Data {
+	d := Sample(event)
+	d.Stats = nil
+	d.SinceLast = nil
+	d.SinceStart = nil
+	if event != "ore_found" {
+		d.Ore = nil
+	}
+	return d
+}
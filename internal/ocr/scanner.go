@@ -3,6 +3,8 @@ package ocr
 import (
 	"forger-companion/internal/config"
 	"forger-companion/internal/data"
+	"forger-companion/internal/metrics"
+	"hash/fnv"
 	"image"
 	"image/png"
 	"log"
@@ -10,39 +12,61 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/kbinani/screenshot"
-	"github.com/otiai10/gosseract/v2"
 )
 
 type DetectedOre struct {
-	Name       string
-	Count      int
-	Rarity     string
-	Multiplier float64
+	Name       string      `json:"name"`
+	Count      int         `json:"count"`
+	Rarity     data.Rarity `json:"rarity"`
+	Multiplier float64     `json:"multiplier"`
+	SellPrice  int         `json:"sell_price"`
 }
 
+var (
+	ocrDuration = metrics.NewHistogram("forger_ocr_duration_seconds", "Time spent recognizing text, by operation.",
+		[]float64{0.05, 0.1, 0.25, 0.5, 1, 2, 5, 10}, "operation")
+	ocrConfidence = metrics.NewGauge("forger_ocr_confidence_ratio", "Mean word confidence of the last recognition, by operation.", "operation")
+	scanSkips     = metrics.NewCounter("forger_scan_skips_total", "Scans that reused the previous text because the capture hadn't changed, by operation.", "operation")
+)
+
+// Scanner is shared by the scan loop, the macro and the web API; mu keeps
+// them from using the Tesseract client at the same time.
 type Scanner struct {
-	client *gosseract.Client
+	mu     sync.Mutex
+	engine *engine
+	last   map[string]lastRead // by operation
+}
+
+// lastRead is the previous capture of an operation, so an unchanged screen
+// isn't recognized again.
+type lastRead struct {
+	hash uint64
+	text string
 }
 
 func NewScanner() *Scanner {
-	client := gosseract.NewClient()
-	client.SetLanguage("eng")
-	client.SetPageSegMode(gosseract.PSM_AUTO)
-	
 	return &Scanner{
-		client: client,
+		engine: newEngine(),
+		last:   make(map[string]lastRead),
 	}
 }
 
 func (s *Scanner) Close() {
-	if s.client != nil {
-		s.client.Close()
+	if s.engine != nil {
+		s.engine.close()
 	}
 }
 
+// CaptureRegion grabs region from the screen, or the primary display when
+// region is nil.
 func (s *Scanner) CaptureRegion(region *config.Region) (image.Image, error) {
+	if region == nil {
+		return screenshot.CaptureRect(screenshot.GetDisplayBounds(0))
+	}
 	bounds := image.Rect(region.X, region.Y, region.X+region.Width, region.Y+region.Height)
 	img, err := screenshot.CaptureRect(bounds)
 	if err != nil {
@@ -52,25 +76,66 @@ func (s *Scanner) CaptureRegion(region *config.Region) (image.Image, error) {
 }
 
 func (s *Scanner) ScanForOres(region *config.Region) (map[string]DetectedOre, error) {
-	img, err := s.CaptureRegion(region)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	text, err := s.recognize("ores", region)
 	if err != nil {
 		return nil, err
 	}
 
+	return s.parseOres(text), nil
+}
+
+// recognize captures region and returns its text, recording latency and
+// confidence under op. If the capture is identical to op's previous one
+// the earlier text is returned without running Tesseract. The caller holds
+// s.mu.
+func (s *Scanner) recognize(op string, region *config.Region) (string, error) {
+	img, err := s.CaptureRegion(region)
+	if err != nil {
+		return "", err
+	}
+
+	hash, hashed := imageHash(img)
+	if last, ok := s.last[op]; ok && hashed && last.hash == hash {
+		scanSkips.Inc(op)
+		return last.text, nil
+	}
+
 	// Save temp image for gosseract
-	tmpFile := "temp_scan.png"
+	tmpFile := "temp_" + op + ".png"
 	if err := saveImage(img, tmpFile); err != nil {
-		return nil, err
+		return "", err
 	}
 	defer os.Remove(tmpFile)
 
-	s.client.SetImage(tmpFile)
-	text, err := s.client.Text()
+	start := time.Now()
+	text, confidence, err := s.engine.read(tmpFile)
 	if err != nil {
-		return nil, err
+		return "", err
+	}
+	ocrDuration.Observe(time.Since(start).Seconds(), op)
+	if confidence >= 0 {
+		ocrConfidence.Set(confidence, op)
 	}
 
-	return s.parseOres(text), nil
+	if hashed {
+		s.last[op] = lastRead{hash: hash, text: text}
+	}
+	return text, nil
+}
+
+// imageHash fingerprints a screen capture. It reports false for image
+// types it can't read directly.
+func imageHash(img image.Image) (uint64, bool) {
+	rgba, ok := img.(*image.RGBA)
+	if !ok {
+		return 0, false
+	}
+	h := fnv.New64a()
+	h.Write(rgba.Pix)
+	return h.Sum64(), true
 }
 
 func (s *Scanner) parseOres(text string) map[string]DetectedOre {
@@ -81,54 +146,40 @@ func (s *Scanner) parseOres(text string) map[string]DetectedOre {
 	countPattern := regexp.MustCompile(`x\s*(\d+)`)
 
 	for i, line := range lines {
-		lineLower := strings.ToLower(strings.TrimSpace(line))
-		
-		// Check each ore name
-		for oreName, oreData := range data.Ores {
-			oreNameLower := strings.ToLower(oreName)
-			baseName := strings.TrimSuffix(oreNameLower, " ore")
-			
-			if strings.Contains(lineLower, oreNameLower) || strings.Contains(lineLower, baseName) {
-				count := 1
-				
-				// Look for count in nearby lines
-				for j := i; j < len(lines) && j < i+3; j++ {
-					if matches := countPattern.FindStringSubmatch(lines[j]); len(matches) > 1 {
-						if c, err := strconv.Atoi(matches[1]); err == nil && c > 0 && c < 100 {
-							count = c
-							break
-						}
-					}
-				}
-				
-				detected[oreName] = DetectedOre{
-					Name:       oreName,
-					Count:      count,
-					Rarity:     oreData.Rarity,
-					Multiplier: oreData.Multiplier,
+		ore, ok := data.Match(line)
+		if !ok {
+			continue
+		}
+
+		count := 1
+
+		// Look for count in nearby lines
+		for j := i; j < len(lines) && j < i+3; j++ {
+			if matches := countPattern.FindStringSubmatch(lines[j]); len(matches) > 1 {
+				if c, err := strconv.Atoi(matches[1]); err == nil && c > 0 && c < 100 {
+					count = c
+					break
 				}
-				break
 			}
 		}
+
+		detected[ore.Name] = DetectedOre{
+			Name:       ore.Name,
+			Count:      count,
+			Rarity:     ore.Rarity,
+			Multiplier: ore.Multiplier,
+			SellPrice:  ore.SellPrice,
+		}
 	}
 
 	return detected
 }
 
 func (s *Scanner) DetectForgeUI(region *config.Region) (bool, bool, error) {
-	img, err := s.CaptureRegion(region)
-	if err != nil {
-		return false, false, err
-	}
-
-	tmpFile := "temp_detect.png"
-	if err := saveImage(img, tmpFile); err != nil {
-		return false, false, err
-	}
-	defer os.Remove(tmpFile)
+	s.mu.Lock()
+	defer s.mu.Unlock()
 
-	s.client.SetImage(tmpFile)
-	text, err := s.client.Text()
+	text, err := s.recognize("forge_ui", region)
 	if err != nil {
 		return false, false, err
 	}
@@ -153,25 +204,21 @@ func (s *Scanner) DetectForgeUI(region *config.Region) (bool, bool, error) {
 }
 
 type Stats struct {
-	LegendaryOres map[string]int
-	Level         int
-	Money         int
+	LegendaryOres map[string]int `json:"legendary_ores"`
+	Level         int            `json:"level"`
+	Money         int            `json:"money"`
 }
 
-func (s *Scanner) ScanForStats(region *config.Region) (*Stats, error) {
-	img, err := s.CaptureRegion(region)
-	if err != nil {
-		return nil, err
-	}
+// SellValue is what the tracked legendary/mythic ores would sell for.
+func (s *Stats) SellValue() int {
+	return data.SellValue(s.LegendaryOres)
+}
 
-	tmpFile := "temp_stats.png"
-	if err := saveImage(img, tmpFile); err != nil {
-		return nil, err
-	}
-	defer os.Remove(tmpFile)
+func (s *Scanner) ScanForStats(region *config.Region) (*Stats, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 
-	s.client.SetImage(tmpFile)
-	text, err := s.client.Text()
+	text, err := s.recognize("stats", region)
 	if err != nil {
 		return nil, err
 	}
@@ -185,17 +232,14 @@ func (s *Scanner) ScanForStats(region *config.Region) (*Stats, error) {
 
 	// Scan for legendary/mythic ores
 	countPattern := regexp.MustCompile(`x\s*(\d+)`)
-	for _, oreName := range data.LegendaryMythic {
-		oreNameLower := strings.ToLower(oreName)
-		if strings.Contains(textLower, oreNameLower) {
-			for _, line := range lines {
-				if strings.Contains(strings.ToLower(line), oreNameLower) {
-					if matches := countPattern.FindStringSubmatch(line); len(matches) > 1 {
-						if count, err := strconv.Atoi(matches[1]); err == nil {
-							stats.LegendaryOres[oreName] += count
-						}
-					}
-				}
+	for _, line := range lines {
+		ore, ok := data.Match(line)
+		if !ok || !ore.Rarity.AtLeast(data.Legendary) {
+			continue
+		}
+		if matches := countPattern.FindStringSubmatch(line); len(matches) > 1 {
+			if count, err := strconv.Atoi(matches[1]); err == nil {
+				stats.LegendaryOres[ore.Name] += count
 			}
 		}
 	}
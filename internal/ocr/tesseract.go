@@ -0,0 +1,45 @@
+//go:build cgo
+
+package ocr
+
+import "github.com/otiai10/gosseract/v2"
+
+// engine reads text with Tesseract.
+type engine struct {
+	client *gosseract.Client
+}
+
+func newEngine() *engine {
+	client := gosseract.NewClient()
+	client.SetLanguage("eng")
+	client.SetPageSegMode(gosseract.PSM_AUTO)
+	return &engine{client: client}
+}
+
+func (e *engine) close() {
+	e.client.Close()
+}
+
+// read returns the text in the image file at path and the mean word
+// confidence from 0 to 1, or -1 when no words were found.
+func (e *engine) read(path string) (string, float64, error) {
+	e.client.SetImage(path)
+	// Bounding boxes run recognition; Text then reuses it rather than
+	// recognizing the image twice.
+	boxes, err := e.client.GetBoundingBoxes(gosseract.RIL_WORD)
+	if err != nil {
+		return "", 0, err
+	}
+	text, err := e.client.Text()
+	if err != nil {
+		return "", 0, err
+	}
+	if len(boxes) == 0 {
+		return text, -1, nil
+	}
+	var sum float64
+	for _, b := range boxes {
+		sum += b.Confidence
+	}
+	return text, sum / float64(len(boxes)) / 100, nil
+}
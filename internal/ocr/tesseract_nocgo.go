@@ -0,0 +1,19 @@
+//go:build !cgo
+
+package ocr
+
+import "errors"
+
+// Builds without cgo can't link Tesseract. They still compile, so packages
+// that only need the OCR types can be built and tested anywhere.
+type engine struct{}
+
+var errNoEngine = errors.New("text recognition needs a build with cgo and Tesseract")
+
+func newEngine() *engine { return &engine{} }
+
+func (e *engine) close() {}
+
+func (e *engine) read(path string) (string, float64, error) {
+	return "", 0, errNoEngine
+}
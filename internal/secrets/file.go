@@ -0,0 +1,322 @@
+package secrets
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// PassphraseEnv, when set, derives the file store key from a passphrase
+// instead of the generated key file.
+const PassphraseEnv = "FORGER_SECRETS_PASSPHRASE"
+
+// The secrets file is magic, a random salt for the passphrase KDF, then
+// the AES-GCM nonce and ciphertext. Files written before the header was
+// added are just nonce and ciphertext; they are read and rewritten.
+var fileMagic = []byte("FCS1")
+
+const (
+	saltSize        = 16
+	kdfIterations   = 600000
+	legacyKeyRounds = 200000
+)
+
+// FileStore keeps secrets in an AES-GCM encrypted JSON file. The key comes
+// from PassphraseEnv or from a random key file readable only by the user,
+// kept in the user config directory rather than next to the secrets.
+type FileStore struct {
+	path      string
+	keyPath   string
+	legacyKey string // key file location before it moved out of dir
+
+	mu          sync.Mutex
+	salt        []byte // of the file on disk
+	derived     []byte // passphrase key for derivedSalt
+	derivedSalt []byte
+}
+
+func NewFileStore(dir string) *FileStore {
+	keyDir, err := os.UserConfigDir()
+	if err != nil {
+		log.Printf("[Secrets] No user config directory (%v), keeping the key with the secrets", err)
+		keyDir = dir
+	} else {
+		keyDir = filepath.Join(keyDir, Service)
+	}
+	return &FileStore{
+		path:      filepath.Join(dir, "secrets.enc"),
+		keyPath:   filepath.Join(keyDir, "secrets.key"),
+		legacyKey: filepath.Join(dir, "secrets.key"),
+	}
+}
+
+func (f *FileStore) Get(key string) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	values, err := f.load()
+	if err != nil {
+		return "", err
+	}
+	value, ok := values[key]
+	if !ok {
+		return "", ErrNotFound
+	}
+	return value, nil
+}
+
+func (f *FileStore) Set(key, value string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	values, err := f.load()
+	if err != nil {
+		return err
+	}
+	values[key] = value
+	return f.save(values)
+}
+
+func (f *FileStore) Delete(key string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	values, err := f.load()
+	if err != nil {
+		return err
+	}
+	delete(values, key)
+	return f.save(values)
+}
+
+func (f *FileStore) load() (map[string]string, error) {
+	values := make(map[string]string)
+
+	data, err := os.ReadFile(f.path)
+	if os.IsNotExist(err) {
+		return values, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	legacy := !bytes.HasPrefix(data, fileMagic)
+	var key, sealed []byte
+	if legacy {
+		key, err = f.legacyCipherKey()
+		sealed = data
+	} else {
+		if len(data) < len(fileMagic)+saltSize {
+			return nil, errors.New("secrets file is corrupt")
+		}
+		f.salt = append([]byte{}, data[len(fileMagic):len(fileMagic)+saltSize]...)
+		key, err = f.cipherKey(f.salt)
+		sealed = data[len(fileMagic)+saltSize:]
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return nil, errors.New("secrets file is corrupt")
+	}
+	nonce, ciphertext := sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():]
+	plain, err := gcm.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return nil, errors.New("can't decrypt secrets file: wrong key or passphrase")
+	}
+
+	if err := json.Unmarshal(plain, &values); err != nil {
+		return nil, err
+	}
+	if legacy {
+		if err := f.save(values); err != nil {
+			return nil, err
+		}
+		log.Println("[Secrets] Upgraded secrets file to the salted format")
+	}
+	return values, nil
+}
+
+func (f *FileStore) save(values map[string]string) error {
+	plain, err := json.Marshal(values)
+	if err != nil {
+		return err
+	}
+
+	if f.salt == nil {
+		f.salt = make([]byte, saltSize)
+		if _, err := io.ReadFull(rand.Reader, f.salt); err != nil {
+			f.salt = nil
+			return err
+		}
+	}
+	key, err := f.cipherKey(f.salt)
+	if err != nil {
+		return err
+	}
+	gcm, err := newGCM(key)
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return err
+	}
+
+	data := append(append([]byte{}, fileMagic...), f.salt...)
+	data = append(data, nonce...)
+	data = gcm.Seal(data, nonce, plain, nil)
+
+	tmp := f.path + ".tmp"
+	if err := writeSynced(tmp, data); err != nil {
+		return err
+	}
+	return os.Rename(tmp, f.path)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// cipherKey returns the key for a file with salt: the passphrase run
+// through PBKDF2, or the key file.
+func (f *FileStore) cipherKey(salt []byte) ([]byte, error) {
+	passphrase := os.Getenv(PassphraseEnv)
+	if passphrase == "" {
+		return f.keyFile()
+	}
+	if f.derived == nil || !bytes.Equal(f.derivedSalt, salt) {
+		f.derivedSalt = append([]byte{}, salt...)
+		f.derived = pbkdf2([]byte(passphrase), salt, kdfIterations, 32)
+	}
+	return f.derived, nil
+}
+
+// legacyCipherKey returns the key files without a header were written with.
+func (f *FileStore) legacyCipherKey() ([]byte, error) {
+	passphrase := os.Getenv(PassphraseEnv)
+	if passphrase == "" {
+		return f.keyFile()
+	}
+	sum := sha256.Sum256([]byte(Service + ":" + passphrase))
+	for i := 0; i < legacyKeyRounds; i++ {
+		sum = sha256.Sum256(append(sum[:], passphrase...))
+	}
+	return sum[:], nil
+}
+
+func (f *FileStore) keyFile() ([]byte, error) {
+	if err := f.moveLegacyKey(); err != nil {
+		return nil, err
+	}
+
+	key, err := os.ReadFile(f.keyPath)
+	if err == nil {
+		if len(key) != 32 {
+			return nil, errors.New("secrets key file is corrupt")
+		}
+		return key, nil
+	}
+	if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	key = make([]byte, 32)
+	if _, err := io.ReadFull(rand.Reader, key); err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(f.keyPath), 0700); err != nil {
+		return nil, err
+	}
+	if err := writeSynced(f.keyPath, key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// moveLegacyKey moves a key file left next to the secrets by an older
+// version to keyPath.
+func (f *FileStore) moveLegacyKey() error {
+	if f.legacyKey == f.keyPath {
+		return nil
+	}
+	key, err := os.ReadFile(f.legacyKey)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(f.keyPath); err == nil {
+		return nil // already moved; leave the old one for the user to check
+	}
+	if err := os.MkdirAll(filepath.Dir(f.keyPath), 0700); err != nil {
+		return err
+	}
+	if err := writeSynced(f.keyPath, key); err != nil {
+		return err
+	}
+	log.Printf("[Secrets] Moved secrets key to %s", f.keyPath)
+	return os.Remove(f.legacyKey)
+}
+
+// writeSynced writes data to path, readable only by the user, and flushes
+// it to disk before returning.
+func writeSynced(path string, data []byte) error {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// pbkdf2 is PBKDF2-HMAC-SHA256 from RFC 8018.
+func pbkdf2(password, salt []byte, iterations, keyLen int) []byte {
+	prf := hmac.New(sha256.New, password)
+	var key []byte
+	for block := uint32(1); len(key) < keyLen; block++ {
+		prf.Reset()
+		prf.Write(salt)
+		binary.Write(prf, binary.BigEndian, block)
+		u := prf.Sum(nil)
+		t := append([]byte{}, u...)
+		for i := 1; i < iterations; i++ {
+			prf.Reset()
+			prf.Write(u)
+			u = prf.Sum(u[:0])
+			for j := range t {
+				t[j] ^= u[j]
+			}
+		}
+		key = append(key, t...)
+	}
+	return key[:keyLen]
+}
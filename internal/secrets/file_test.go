@@ -0,0 +1,113 @@
+package secrets
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// newTestStore keeps the secrets and the key file in temp dirs.
+func newTestStore(t *testing.T) (*FileStore, string) {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, ".config"))
+	dir := t.TempDir()
+	return NewFileStore(dir), dir
+}
+
+func TestFileStoreRoundTrip(t *testing.T) {
+	tests := []struct {
+		name       string
+		passphrase string
+	}{
+		{"key file", ""},
+		{"passphrase", "correct horse battery staple"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(PassphraseEnv, tt.passphrase)
+			store, dir := newTestStore(t)
+
+			values := map[string]string{
+				"settings/web.tokens[0].token#aa": "first",
+				"settings/web.tokens[1].token#bb": "second",
+			}
+			for key, value := range values {
+				if err := store.Set(key, value); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			raw, err := os.ReadFile(filepath.Join(dir, "secrets.enc"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.HasPrefix(raw, fileMagic) || bytes.Contains(raw, []byte("first")) {
+				t.Error("secrets file isn't encrypted in the salted format")
+			}
+
+			reopened := NewFileStore(dir)
+			for key, want := range values {
+				if got, err := reopened.Get(key); err != nil || got != want {
+					t.Errorf("Get(%q) = %q, %v; want %q", key, got, err, want)
+				}
+			}
+
+			if err := reopened.Delete("settings/web.tokens[0].token#aa"); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := NewFileStore(dir).Get("settings/web.tokens[0].token#aa"); !errors.Is(err, ErrNotFound) {
+				t.Errorf("deleted secret: err = %v, want ErrNotFound", err)
+			}
+			if got, _ := NewFileStore(dir).Get("settings/web.tokens[1].token#bb"); got != "second" {
+				t.Errorf("other secret = %q after delete, want %q", got, "second")
+			}
+		})
+	}
+}
+
+func TestFileStoreWrongKey(t *testing.T) {
+	tests := []struct {
+		name       string
+		passphrase string
+	}{
+		{"wrong passphrase", "wrong"},
+		{"passphrase removed", ""}, // falls back to the key file
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(PassphraseEnv, "right")
+			store, dir := newTestStore(t)
+			if err := store.Set("key", "value"); err != nil {
+				t.Fatal(err)
+			}
+			before, _ := os.ReadFile(filepath.Join(dir, "secrets.enc"))
+
+			t.Setenv(PassphraseEnv, tt.passphrase)
+			reopened := NewFileStore(dir)
+			if _, err := reopened.Get("key"); err == nil || errors.Is(err, ErrNotFound) {
+				t.Errorf("Get with the wrong key: err = %v, want a decryption error", err)
+			}
+			if err := reopened.Set("other", "value"); err == nil {
+				t.Error("Set with the wrong key overwrote the secrets file")
+			}
+			if after, _ := os.ReadFile(filepath.Join(dir, "secrets.enc")); !bytes.Equal(before, after) {
+				t.Error("secrets file changed")
+			}
+		})
+	}
+}
+
+func TestPBKDF2(t *testing.T) {
+	// RFC 7914 section 11
+	want := "55ac046e56e3089fec1691c22544b605f94185216dde0465e68b9d57c20dacbc" +
+		"49ca9cccf179b645991664b39d77ef317c71b845b1e30bd509112041d3a19783"
+	got := hex.EncodeToString(pbkdf2([]byte("passwd"), []byte("salt"), 1, 64))
+	if got != want {
+		t.Errorf("pbkdf2 = %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,113 @@
+//go:build !windows
+
+package secrets
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// Exit codes the keyring tools use for a missing item.
+const (
+	securityNotFound   = 44 // errSecItemNotFound
+	secretToolNotFound = 1  // with nothing on stderr
+)
+
+// keyring talks to the OS keyring through its command-line tool: macOS
+// `security` or the freedesktop `secret-tool` on Linux. Secrets are passed
+// on stdin, never on the command line where other users could see them.
+type keyring struct {
+	tool string
+}
+
+func newKeyring() (Store, bool) {
+	switch runtime.GOOS {
+	case "darwin":
+		if path, err := exec.LookPath("security"); err == nil {
+			return &keyring{tool: path}, true
+		}
+	case "linux":
+		// secret-tool needs a session bus, which headless boxes lack
+		if os.Getenv("DBUS_SESSION_BUS_ADDRESS") == "" {
+			return nil, false
+		}
+		if path, err := exec.LookPath("secret-tool"); err == nil {
+			return &keyring{tool: path}, true
+		}
+	}
+	return nil, false
+}
+
+func (k *keyring) Get(key string) (string, error) {
+	var cmd *exec.Cmd
+	if runtime.GOOS == "darwin" {
+		cmd = exec.Command(k.tool, "find-generic-password", "-s", Service, "-a", key, "-w")
+	} else {
+		cmd = exec.Command(k.tool, "lookup", "service", Service, "key", key)
+	}
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	var exit *exec.ExitError
+	if errors.As(err, &exit) {
+		code := exit.ExitCode()
+		if runtime.GOOS == "darwin" && code == securityNotFound ||
+			runtime.GOOS != "darwin" && code == secretToolNotFound && stderr.Len() == 0 {
+			return "", ErrNotFound
+		}
+	}
+	if err != nil {
+		return "", fmt.Errorf("%s: %v: %s", cmd.Args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	value := strings.TrimRight(string(out), "\n")
+	if value == "" {
+		return "", ErrNotFound
+	}
+	return value, nil
+}
+
+func (k *keyring) Set(key, value string) error {
+	var cmd *exec.Cmd
+	if runtime.GOOS == "darwin" {
+		// In interactive mode security reads the command from stdin. The
+		// value goes as hex so it needs no quoting.
+		cmd = exec.Command(k.tool, "-i")
+		cmd.Stdin = strings.NewReader(fmt.Sprintf("add-generic-password -U -s %s -a %s -X %s\n",
+			quote(Service), quote(key), hex.EncodeToString([]byte(value))))
+	} else {
+		cmd = exec.Command(k.tool, "store", "--label", Service+": "+key, "service", Service, "key", key)
+		cmd.Stdin = strings.NewReader(value)
+	}
+	return run(cmd)
+}
+
+func (k *keyring) Delete(key string) error {
+	var cmd *exec.Cmd
+	if runtime.GOOS == "darwin" {
+		cmd = exec.Command(k.tool, "delete-generic-password", "-s", Service, "-a", key)
+	} else {
+		cmd = exec.Command(k.tool, "clear", "service", Service, "key", key)
+	}
+	return run(cmd)
+}
+
+// quote makes s a single argument to security's interactive mode.
+func quote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+func run(cmd *exec.Cmd) error {
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s: %v: %s", cmd.Args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+package secrets
+
+import (
+	"errors"
+	"syscall"
+	"unsafe"
+)
+
+const (
+	credTypeGeneric         = 1
+	credPersistLocalMachine = 2
+	errorNotFound           = syscall.Errno(1168)
+	credMaxBlobSize         = 5 * 512
+)
+
+var (
+	advapi32       = syscall.NewLazyDLL("advapi32.dll")
+	procCredReadW  = advapi32.NewProc("CredReadW")
+	procCredWriteW = advapi32.NewProc("CredWriteW")
+	procCredDelete = advapi32.NewProc("CredDeleteW")
+	procCredFree   = advapi32.NewProc("CredFree")
+)
+
+// credential is the Win32 CREDENTIALW structure.
+type credential struct {
+	Flags              uint32
+	Type               uint32
+	TargetName         *uint16
+	Comment            *uint16
+	LastWritten        syscall.Filetime
+	CredentialBlobSize uint32
+	CredentialBlob     *byte
+	Persist            uint32
+	AttributeCount     uint32
+	Attributes         uintptr
+	TargetAlias        *uint16
+	UserName           *uint16
+}
+
+// credManager keeps secrets as generic credentials in the Windows
+// Credential Manager, under "forger-companion:<key>".
+type credManager struct{}
+
+func newKeyring() (Store, bool) {
+	if err := procCredReadW.Find(); err != nil {
+		return nil, false
+	}
+	return credManager{}, true
+}
+
+func target(key string) (*uint16, error) {
+	return syscall.UTF16PtrFromString(Service + ":" + key)
+}
+
+func (credManager) Get(key string) (string, error) {
+	name, err := target(key)
+	if err != nil {
+		return "", err
+	}
+	var cred *credential
+	ok, _, err := procCredReadW.Call(uintptr(unsafe.Pointer(name)), credTypeGeneric, 0, uintptr(unsafe.Pointer(&cred)))
+	if ok == 0 {
+		if errors.Is(err, errorNotFound) {
+			return "", ErrNotFound
+		}
+		return "", err
+	}
+	defer procCredFree.Call(uintptr(unsafe.Pointer(cred)))
+
+	if cred.CredentialBlobSize == 0 {
+		return "", ErrNotFound
+	}
+	return string(unsafe.Slice(cred.CredentialBlob, cred.CredentialBlobSize)), nil
+}
+
+func (credManager) Set(key, value string) error {
+	if len(value) > credMaxBlobSize {
+		return errors.New("secret is too long for the Credential Manager")
+	}
+	name, err := target(key)
+	if err != nil {
+		return err
+	}
+	user, err := syscall.UTF16PtrFromString(key)
+	if err != nil {
+		return err
+	}
+	blob := []byte(value)
+	cred := credential{
+		Type:               credTypeGeneric,
+		TargetName:         name,
+		CredentialBlobSize: uint32(len(blob)),
+		Persist:            credPersistLocalMachine,
+		UserName:           user,
+	}
+	if len(blob) > 0 {
+		cred.CredentialBlob = &blob[0]
+	}
+	if ok, _, err := procCredWriteW.Call(uintptr(unsafe.Pointer(&cred)), 0); ok == 0 {
+		return err
+	}
+	return nil
+}
+
+func (credManager) Delete(key string) error {
+	name, err := target(key)
+	if err != nil {
+		return err
+	}
+	ok, _, err := procCredDelete.Call(uintptr(unsafe.Pointer(name)), credTypeGeneric, 0)
+	if ok == 0 && !errors.Is(err, errorNotFound) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,28 @@
+package secrets
+
+import (
+	"errors"
+	"log"
+)
+
+// Service is the name secrets are filed under in the OS keyring.
+const Service = "forger-companion"
+
+var ErrNotFound = errors.New("secret not found")
+
+type Store interface {
+	Get(key string) (string, error)
+	Set(key, value string) error
+	Delete(key string) error
+}
+
+// Open returns the OS keyring (the macOS Keychain, the freedesktop secret
+// service or the Windows Credential Manager) when one is usable and
+// otherwise an encrypted file store in dir.
+func Open(dir string) Store {
+	if kr, ok := newKeyring(); ok {
+		return kr
+	}
+	log.Println("[Secrets] No OS keyring available, using encrypted file store")
+	return NewFileStore(dir)
+}
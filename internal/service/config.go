@@ -0,0 +1,171 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"forger-companion/internal/config"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+var ErrMacroRunning = errors.New("stop the macro first")
+
+// ConfigService changes settings on behalf of the front-ends. Each change
+// is made on a copy, validated and saved, and only then published to the
+// watcher's subscribers; the settings others are reading never change.
+type ConfigService struct {
+	watcher *config.Watcher
+	macro   *MacroController
+
+	mu sync.Mutex // serializes edits
+}
+
+func newConfigService(cfg *config.Config, macro *MacroController) *ConfigService {
+	return &ConfigService{watcher: config.NewWatcher(cfg), macro: macro}
+}
+
+// Current returns the settings in effect. They must not be modified; use
+// Edit or Update to change them and Subscribe to hear about it.
+func (c *ConfigService) Current() *config.Config {
+	return c.watcher.Current()
+}
+
+// Subscribe registers fn to be called with the new settings after every
+// change.
+func (c *ConfigService) Subscribe(fn func(*config.Config)) {
+	c.watcher.Subscribe(fn)
+}
+
+// Update merges a partial settings JSON object in, then validates and
+// saves the result.
+func (c *ConfigService) Update(patch []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	next, err := c.Current().Update(patch)
+	if err != nil {
+		return err
+	}
+	return c.publish(next)
+}
+
+// Edit applies fn to a copy of the current settings. If fn succeeds and
+// the result is valid and saved, the copy becomes the current settings.
+func (c *ConfigService) Edit(fn func(*config.Config) error) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	next := c.Current().Clone()
+	if err := fn(next); err != nil {
+		return err
+	}
+	if err := next.Validate(); err != nil {
+		return err
+	}
+	return c.publish(next)
+}
+
+func (c *ConfigService) publish(next *config.Config) error {
+	if err := next.Save(); err != nil {
+		return err
+	}
+	c.watcher.Replace(next)
+	return nil
+}
+
+// SetRegion saves a screen region such as "ores_panel".
+func (c *ConfigService) SetRegion(name string, region *config.Region) error {
+	return c.Edit(func(cfg *config.Config) error {
+		cfg.Regions[name] = region
+		return nil
+	})
+}
+
+// SetButton saves a macro button such as "sell_tab".
+func (c *ConfigService) SetButton(name string, button *config.MacroButton) error {
+	return c.Edit(func(cfg *config.Config) error {
+		cfg.MacroButtons[name] = button
+		return nil
+	})
+}
+
+// CompleteSetup marks first-run setup done once everything the macro and
+// scanner need has been captured.
+func (c *ConfigService) CompleteSetup() error {
+	return c.Edit(func(cfg *config.Config) error {
+		if missing := cfg.MissingSetup(); len(missing) > 0 {
+			return fmt.Errorf("still to set up: %s", strings.Join(missing, ", "))
+		}
+		cfg.SetupComplete = true
+		return nil
+	})
+}
+
+type Profiles struct {
+	Active   string   `json:"active"` // "" for the base settings
+	Profiles []string `json:"profiles"`
+}
+
+func (c *ConfigService) Profiles() (Profiles, error) {
+	names, err := config.Profiles()
+	return Profiles{Active: c.Current().Profile(), Profiles: names}, err
+}
+
+// SwitchProfile loads another profile so the macro and webhooks pick it up
+// without a restart.
+func (c *ConfigService) SwitchProfile(name string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.macro.IsRunning() {
+		return ErrMacroRunning
+	}
+	cfg, err := config.SwitchProfile(name)
+	if err != nil {
+		return err
+	}
+	c.watcher.Replace(cfg)
+	return nil
+}
+
+// ProfileAction runs a named profile action: "switch", "create", "clone"
+// (from source, empty for the base settings) or "delete".
+func (c *ConfigService) ProfileAction(action, name, source string) error {
+	switch action {
+	case "switch":
+		return c.SwitchProfile(name)
+	case "create":
+		return config.CreateProfile(name)
+	case "clone":
+		return config.CloneProfile(source, name)
+	case "delete":
+		return config.DeleteProfile(name)
+	}
+	return fmt.Errorf("unknown action %q", action)
+}
+
+// PairDevice creates a web API token and returns the link that signs a
+// browser in with it.
+func (c *ConfigService) PairDevice(name, scope string) (string, error) {
+	var token string
+	err := c.Edit(func(cfg *config.Config) error {
+		var err error
+		token, err = cfg.Web.AddToken(name, scope)
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+	return c.Current().Web.URL() + "pair?token=" + url.QueryEscape(token), nil
+}
+
+// RevokeDevice removes a paired device's token.
+func (c *ConfigService) RevokeDevice(name string) error {
+	return c.Edit(func(cfg *config.Config) error {
+		for i, t := range cfg.Web.Tokens {
+			if t.Name == name {
+				cfg.Web.Tokens = append(cfg.Web.Tokens[:i], cfg.Web.Tokens[i+1:]...)
+				return nil
+			}
+		}
+		return fmt.Errorf("no device named %q", name)
+	})
+}
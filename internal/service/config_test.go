@@ -0,0 +1,129 @@
+package service
+
+import (
+	"errors"
+	"forger-companion/internal/config"
+	"os"
+	"testing"
+)
+
+func TestSetRegionPublishesCopy(t *testing.T) {
+	svc, m := newTestService(t, nil)
+	before := svc.Config.Current()
+
+	region := &config.Region{X: 10, Y: 20, Width: 300, Height: 200}
+	if err := svc.Config.SetRegion("ores_panel", region); err != nil {
+		t.Fatal(err)
+	}
+
+	if before.Regions["ores_panel"] != nil {
+		t.Error("settings already handed out were changed")
+	}
+	after := svc.Config.Current()
+	if after == before || after.Regions["ores_panel"] == nil {
+		t.Fatal("new settings weren't published")
+	}
+	if len(m.configs) != 1 || m.configs[0] != after {
+		t.Errorf("macro got %d config changes, want the new settings once", len(m.configs))
+	}
+
+	saved, err := config.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := saved.Regions["ores_panel"]; got == nil || *got != *region {
+		t.Errorf("saved region = %v, want %v", got, region)
+	}
+}
+
+func TestEditRejectsInvalidSettings(t *testing.T) {
+	svc, m := newTestService(t, nil)
+	before := svc.Config.Current()
+
+	err := svc.Config.SetRegion("ores_panel", &config.Region{X: 10, Y: 20})
+	if err == nil {
+		t.Fatal("empty region was accepted")
+	}
+	if svc.Config.Current() != before || before.Regions["ores_panel"] != nil {
+		t.Error("invalid settings were published")
+	}
+	if len(m.configs) != 0 {
+		t.Error("subscribers heard about invalid settings")
+	}
+	if _, err := os.Stat(before.Path()); !os.IsNotExist(err) {
+		t.Errorf("invalid settings were saved (stat: %v)", err)
+	}
+}
+
+func TestEditKeepsSettingsWhenFnFails(t *testing.T) {
+	svc, _ := newTestService(t, nil)
+	before := svc.Config.Current()
+	fail := errors.New("no")
+
+	err := svc.Config.Edit(func(cfg *config.Config) error {
+		cfg.Regions["ores_panel"] = &config.Region{Width: 1, Height: 1}
+		return fail
+	})
+	if !errors.Is(err, fail) {
+		t.Fatalf("Edit = %v, want %v", err, fail)
+	}
+	if svc.Config.Current() != before || before.Regions["ores_panel"] != nil {
+		t.Error("a failed edit was published")
+	}
+}
+
+func TestUpdateRejectsInvalidPatch(t *testing.T) {
+	svc, _ := newTestService(t, nil)
+	before := svc.Config.Current()
+
+	if err := svc.Config.Update([]byte(`{"preferences": {"opacity": 5}}`)); err == nil {
+		t.Fatal("opacity 5 was accepted")
+	}
+	if svc.Config.Current() != before {
+		t.Error("invalid patch was published")
+	}
+
+	if err := svc.Config.Update([]byte(`{"preferences": {"opacity": 50}}`)); err != nil {
+		t.Fatal(err)
+	}
+	if got := svc.Config.Current().Preferences.Opacity; got != 50 {
+		t.Errorf("opacity = %d, want 50", got)
+	}
+	if before.Preferences.Opacity == 50 {
+		t.Error("settings already handed out were changed")
+	}
+}
+
+func TestSwitchProfile(t *testing.T) {
+	svc, m := newTestService(t, nil)
+	if err := svc.Config.ProfileAction("create", "night", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	m.Start()
+	if err := svc.Config.SwitchProfile("night"); !errors.Is(err, ErrMacroRunning) {
+		t.Fatalf("switch while running = %v, want %v", err, ErrMacroRunning)
+	}
+	if got := svc.Config.Current().Profile(); got != "" {
+		t.Fatalf("profile = %q after refused switch", got)
+	}
+
+	m.Stop()
+	if err := svc.Config.SwitchProfile("night"); err != nil {
+		t.Fatal(err)
+	}
+	if got := svc.Config.Current().Profile(); got != "night" {
+		t.Errorf("profile = %q, want night", got)
+	}
+	if len(m.configs) != 1 || m.configs[0].Profile() != "night" {
+		t.Error("macro didn't get the profile's settings")
+	}
+
+	profiles, err := svc.Config.Profiles()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if profiles.Active != "night" {
+		t.Errorf("active profile = %q, want night", profiles.Active)
+	}
+}
@@ -0,0 +1,45 @@
+package service
+
+import "fmt"
+
+// MacroController adds the actions front-ends offer on top of the macro.
+type MacroController struct {
+	Macro
+}
+
+type MacroStatus struct {
+	State string `json:"state"` // "stopped", "running" or "paused"
+	Cycle int    `json:"cycle"`
+}
+
+func (c *MacroController) Status() MacroStatus {
+	return MacroStatus{State: c.State(), Cycle: c.Cycle()}
+}
+
+// Toggle starts the macro if it is stopped and stops it otherwise.
+func (c *MacroController) Toggle() error {
+	if !c.IsRunning() {
+		return c.Start()
+	}
+	c.Stop()
+	return nil
+}
+
+// Do runs a named action: "start", "stop", "pause", "resume" or "toggle".
+func (c *MacroController) Do(action string) error {
+	switch action {
+	case "start":
+		return c.Start()
+	case "stop":
+		c.Stop()
+	case "pause":
+		c.Pause()
+	case "resume":
+		c.Resume()
+	case "toggle":
+		return c.Toggle()
+	default:
+		return fmt.Errorf("unknown action %q", action)
+	}
+	return nil
+}
@@ -0,0 +1,27 @@
+package service
+
+import "testing"
+
+func TestMacroToggle(t *testing.T) {
+	svc, m := newTestService(t, nil)
+
+	if err := svc.Macro.Toggle(); err != nil {
+		t.Fatal(err)
+	}
+	if !m.IsRunning() || m.starts != 1 {
+		t.Fatal("toggle didn't start a stopped macro")
+	}
+	if err := svc.Macro.Do("toggle"); err != nil {
+		t.Fatal(err)
+	}
+	if m.IsRunning() || m.stops != 1 {
+		t.Error("toggle didn't stop a running macro")
+	}
+
+	if err := svc.Macro.Do("dance"); err == nil {
+		t.Error("unknown action was accepted")
+	}
+	if got := svc.Stats.Snapshot().Macro.State; got != "stopped" {
+		t.Errorf("state = %q, want stopped", got)
+	}
+}
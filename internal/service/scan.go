@@ -0,0 +1,168 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"forger-companion/internal/calculator"
+	"forger-companion/internal/config"
+	"forger-companion/internal/events"
+	"forger-companion/internal/metrics"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	// ErrNoForgeUI is returned by Scan when the forge isn't open or has no
+	// ores placed.
+	ErrNoForgeUI = errors.New("forge UI not detected or no ores placed")
+	ErrNoRegion  = errors.New("ores panel region not selected")
+)
+
+var multiplier = metrics.NewGauge("forger_forge_multiplier", "Total multiplier of the ores in the forge at the last scan.")
+
+// ScanController reads the forge on demand or on an interval. Results,
+// forge UI changes and errors are published on the bus.
+type ScanController struct {
+	cfg     atomic.Pointer[config.Config]
+	scanner Scanner
+	bus     *events.Bus
+
+	mu   sync.Mutex
+	stop chan struct{} // set while the scan loop runs
+
+	// Forge UI state last seen by Scan
+	forgeMu    sync.Mutex
+	forgeKnown bool
+	forgeOpen  bool
+	forgeOres  bool
+}
+
+func newScanController(cfg *config.Config, scanner Scanner, bus *events.Bus) *ScanController {
+	c := &ScanController{scanner: scanner, bus: bus}
+	c.cfg.Store(cfg)
+	return c
+}
+
+// Scan reads the ores panel once and calculates the multiplier.
+func (c *ScanController) Scan() (*calculator.Result, error) {
+	result, err := c.scan()
+	switch {
+	case err == nil:
+		multiplier.Set(result.TotalMultiplier)
+		c.bus.Publish(events.Event{
+			Kind:    events.ScanResult,
+			Message: fmt.Sprintf("Multiplier %.2fx with %d ores", result.TotalMultiplier, result.OreCount),
+			Data:    result,
+		})
+	case !errors.Is(err, ErrNoForgeUI):
+		c.bus.Publish(events.Event{Kind: events.Error, Message: "Scan failed", Error: err.Error()})
+	}
+	return result, err
+}
+
+func (c *ScanController) scan() (*calculator.Result, error) {
+	region := c.cfg.Load().Regions["ores_panel"]
+	if region == nil {
+		return nil, ErrNoRegion
+	}
+
+	// Check if forge UI is open
+	isForgeUI, hasOres, err := c.scanner.DetectForgeUI(region)
+	if err != nil {
+		return nil, fmt.Errorf("detect forge UI: %w", err)
+	}
+	c.setForgeState(isForgeUI, hasOres)
+	if !isForgeUI || !hasOres {
+		return nil, ErrNoForgeUI
+	}
+
+	// Scan for ores
+	ores, err := c.scanner.ScanForOres(region)
+	if err != nil {
+		return nil, fmt.Errorf("scan ores: %w", err)
+	}
+	return calculator.Calculate(ores), nil
+}
+
+// setForgeState publishes forge_ui when the forge opens, closes or gets
+// ores placed.
+func (c *ScanController) setForgeState(open, hasOres bool) {
+	c.forgeMu.Lock()
+	changed := !c.forgeKnown || open != c.forgeOpen || hasOres != c.forgeOres
+	c.forgeKnown, c.forgeOpen, c.forgeOres = true, open, hasOres
+	c.forgeMu.Unlock()
+	if !changed {
+		return
+	}
+
+	msg := "Forge closed"
+	switch {
+	case open && hasOres:
+		msg = "Forge open with ores"
+	case open:
+		msg = "Forge open, no ores placed"
+	}
+	c.bus.Publish(events.Event{Kind: events.ForgeUI, Message: msg, ForgeOpen: open, HasOres: hasOres})
+}
+
+// Start scans every preferences.scan_interval until Stop.
+func (c *ScanController) Start() error {
+	cfg := c.cfg.Load()
+	if cfg.Regions["ores_panel"] == nil {
+		return ErrNoRegion
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.stop != nil {
+		return nil
+	}
+	c.stop = make(chan struct{})
+	go c.loop(c.stop, cfg.Preferences.ScanInterval.Duration())
+	return nil
+}
+
+func (c *ScanController) Stop() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.stop != nil {
+		close(c.stop)
+		c.stop = nil
+	}
+}
+
+func (c *ScanController) Running() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.stop != nil
+}
+
+func (c *ScanController) loop(stop chan struct{}, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if _, err := c.Scan(); err != nil && !errors.Is(err, ErrNoForgeUI) {
+				log.Printf("[Scan] %v", err)
+			}
+		case <-stop:
+			return
+		}
+	}
+}
+
+// configChanged restarts the scan loop so a new region or interval is used.
+func (c *ScanController) configChanged(cfg *config.Config) {
+	c.cfg.Store(cfg)
+	if !c.Running() {
+		return
+	}
+	c.Stop()
+	if err := c.Start(); err != nil {
+		log.Printf("[Scan] Stopped: %v", err)
+	}
+}
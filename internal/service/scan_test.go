@@ -0,0 +1,47 @@
+package service
+
+import (
+	"errors"
+	"forger-companion/internal/calculator"
+	"forger-companion/internal/config"
+	"forger-companion/internal/data"
+	"forger-companion/internal/events"
+	"forger-companion/internal/ocr"
+	"testing"
+)
+
+func TestScan(t *testing.T) {
+	scanner := &fakeScanner{forgeOpen: true, hasOres: true, ores: map[string]ocr.DetectedOre{
+		"Iron Ore": {Name: "Iron Ore", Count: 2, Rarity: data.Common, Multiplier: 1.5, SellPrice: 10},
+	}}
+	svc, _ := newTestService(t, scanner)
+
+	if _, err := svc.Scan.Scan(); !errors.Is(err, ErrNoRegion) {
+		t.Fatalf("scan without a region = %v, want %v", err, ErrNoRegion)
+	}
+	if err := svc.Config.SetRegion("ores_panel", &config.Region{Width: 100, Height: 100}); err != nil {
+		t.Fatal(err)
+	}
+
+	var published []events.Event
+	svc.Bus.Subscribe(func(e events.Event) { published = append(published, e) })
+
+	result, err := svc.Scan.Scan()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.OreCount != 2 {
+		t.Errorf("ore count = %d, want 2", result.OreCount)
+	}
+	if len(published) != 2 || published[0].Kind != events.ForgeUI || published[1].Kind != events.ScanResult {
+		t.Fatalf("published %v, want forge_ui then scan_result", published)
+	}
+	if published[1].Data.(*calculator.Result) != result {
+		t.Error("scan_result doesn't carry the result")
+	}
+
+	scanner.forgeOpen = false
+	if _, err := svc.Scan.Scan(); !errors.Is(err, ErrNoForgeUI) {
+		t.Errorf("scan with the forge closed = %v, want %v", err, ErrNoForgeUI)
+	}
+}
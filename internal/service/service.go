@@ -0,0 +1,80 @@
+// Package service holds the application state and actions shared by every
+// front-end: the desktop windows, the web server and the command line.
+// Front-ends call into it and follow the event bus; none of it depends on
+// a GUI toolkit. The scanner and macro are passed in, so it builds and its
+// tests run without cgo.
+package service
+
+import (
+	"forger-companion/internal/config"
+	"forger-companion/internal/events"
+	"forger-companion/internal/ocr"
+	"forger-companion/internal/stats"
+	"forger-companion/internal/webhook"
+)
+
+// Scanner reads the forge off the screen. The app uses an *ocr.Scanner.
+type Scanner interface {
+	DetectForgeUI(region *config.Region) (isForgeUI, hasOres bool, err error)
+	ScanForOres(region *config.Region) (map[string]ocr.DetectedOre, error)
+	Close()
+}
+
+// Macro runs the mining macro. The app uses a *macro.Macro.
+type Macro interface {
+	Start() error
+	Stop()
+	Pause()
+	Resume()
+	Wait() // until a stopped macro has saved its session report
+	Close()
+	IsRunning() bool
+	State() string // "stopped", "running" or "paused"
+	Cycle() int
+	Session() *stats.Report
+	LastReport() *stats.Report
+	WebhookStatus() webhook.DeliveryStatus
+	ConfigChanged(cfg *config.Config)
+}
+
+type Service struct {
+	Bus    *events.Bus
+	Scan   *ScanController
+	Macro  *MacroController
+	Config *ConfigService
+	Stats  *StatsService
+
+	scanner Scanner
+}
+
+// New builds the service around a scanner and a macro that publish on
+// bus.
+func New(cfg *config.Config, bus *events.Bus, scanner Scanner, macro Macro) *Service {
+	m := &MacroController{Macro: macro}
+
+	s := &Service{
+		Bus:     bus,
+		Scan:    newScanController(cfg, scanner, bus),
+		Macro:   m,
+		Config:  newConfigService(cfg, m),
+		Stats:   &StatsService{macro: m},
+		scanner: scanner,
+	}
+	s.Config.Subscribe(m.ConfigChanged)
+	s.Config.Subscribe(s.Scan.configChanged)
+	return s
+}
+
+// Start begins watching the settings files for edits.
+func (s *Service) Start() {
+	s.Config.watcher.Start()
+}
+
+// Close stops the macro, waiting for its session report, webhook
+// delivery and the scan loop and releases the OCR engine.
+func (s *Service) Close() {
+	s.Macro.Close()
+	s.Scan.Stop()
+	s.Config.watcher.Stop()
+	s.scanner.Close()
+}
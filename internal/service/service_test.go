@@ -0,0 +1,95 @@
+package service
+
+import (
+	"forger-companion/internal/config"
+	"forger-companion/internal/events"
+	"forger-companion/internal/ocr"
+	"forger-companion/internal/stats"
+	"forger-companion/internal/webhook"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+// fakeScanner reads the same forge every time.
+type fakeScanner struct {
+	forgeOpen, hasOres bool
+	ores               map[string]ocr.DetectedOre
+	err                error
+}
+
+func (s *fakeScanner) DetectForgeUI(*config.Region) (bool, bool, error) {
+	return s.forgeOpen, s.hasOres, s.err
+}
+
+func (s *fakeScanner) ScanForOres(*config.Region) (map[string]ocr.DetectedOre, error) {
+	return s.ores, s.err
+}
+
+func (s *fakeScanner) Close() {}
+
+// fakeMacro records what the service asks of it.
+type fakeMacro struct {
+	mu      sync.Mutex
+	running bool
+	starts  int
+	stops   int
+	configs []*config.Config
+}
+
+func (m *fakeMacro) Start() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.running = true
+	m.starts++
+	return nil
+}
+
+func (m *fakeMacro) Stop() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.running = false
+	m.stops++
+}
+
+func (m *fakeMacro) IsRunning() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.running
+}
+
+func (m *fakeMacro) State() string {
+	if m.IsRunning() {
+		return "running"
+	}
+	return "stopped"
+}
+
+func (m *fakeMacro) ConfigChanged(cfg *config.Config) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.configs = append(m.configs, cfg)
+}
+
+func (m *fakeMacro) Pause()                                {}
+func (m *fakeMacro) Resume()                               {}
+func (m *fakeMacro) Wait()                                 {}
+func (m *fakeMacro) Close()                                {}
+func (m *fakeMacro) Cycle() int                            { return 0 }
+func (m *fakeMacro) Session() *stats.Report                { return nil }
+func (m *fakeMacro) LastReport() *stats.Report             { return nil }
+func (m *fakeMacro) WebhookStatus() webhook.DeliveryStatus { return webhook.DeliveryStatus{} }
+
+// newTestService keeps settings in a temporary directory.
+func newTestService(t *testing.T, scanner *fakeScanner) (*Service, *fakeMacro) {
+	t.Helper()
+	config.SetPath(filepath.Join(t.TempDir(), "settings.json"))
+	t.Cleanup(func() { config.SetPath("") })
+
+	cfg := config.Default()
+	if scanner == nil {
+		scanner = &fakeScanner{}
+	}
+	m := &fakeMacro{}
+	return New(cfg, events.NewBus(), scanner, m), m
+}
@@ -0,0 +1,27 @@
+package service
+
+import (
+	"forger-companion/internal/stats"
+	"forger-companion/internal/webhook"
+)
+
+// StatsService reports how the current and last macro sessions went.
+type StatsService struct {
+	macro *MacroController
+}
+
+type Stats struct {
+	Macro      MacroStatus            `json:"macro"`
+	Session    *stats.Report          `json:"session"`     // nil when the macro isn't running
+	LastReport *stats.Report          `json:"last_report"` // nil until a session ends
+	Webhook    webhook.DeliveryStatus `json:"webhook"`
+}
+
+func (s *StatsService) Snapshot() Stats {
+	return Stats{
+		Macro:      s.macro.Status(),
+		Session:    s.macro.Session(),
+		LastReport: s.macro.LastReport(),
+		Webhook:    s.macro.WebhookStatus(),
+	}
+}
@@ -0,0 +1,205 @@
+package stats
+
+import (
+	"encoding/json"
+	"fmt"
+	"forger-companion/internal/ocr"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxErrors caps how many errors a session report keeps.
+const maxErrors = 20
+
+// Session collects what happens during one macro run. It is safe to read
+// a Snapshot while the macro goroutine records.
+type Session struct {
+	mu          sync.Mutex
+	started     time.Time
+	cycles      int
+	cycleTime   time.Duration
+	sellsOK     int
+	sellsFailed int
+	errors      []string
+	first, last *ocr.Stats
+}
+
+func NewSession() *Session {
+	return &Session{started: time.Now()}
+}
+
+func (s *Session) CycleDone(took time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cycles++
+	s.cycleTime += took
+}
+
+func (s *Session) SellDone(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err != nil {
+		s.sellsFailed++
+		s.addError(err)
+		return
+	}
+	s.sellsOK++
+}
+
+func (s *Session) Error(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.addError(err)
+}
+
+func (s *Session) addError(err error) {
+	if len(s.errors) < maxErrors {
+		s.errors = append(s.errors, fmt.Sprintf("%s %v", time.Now().Format("15:04:05"), err))
+	}
+}
+
+func (s *Session) Observe(stats *ocr.Stats) {
+	if stats == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.first == nil {
+		s.first = stats
+	}
+	s.last = stats
+}
+
+// Report summarizes a session.
+type Report struct {
+	Started         time.Time      `json:"started"`
+	Ended           time.Time      `json:"ended"`
+	DurationSeconds int            `json:"duration_seconds"`
+	Cycles          int            `json:"cycles"`
+	AvgCycleSeconds float64        `json:"avg_cycle_seconds"`
+	SellsSucceeded  int            `json:"sells_succeeded"`
+	SellsFailed     int            `json:"sells_failed"`
+	OresGained      map[string]int `json:"ores_gained"`
+	MoneyStart      int            `json:"money_start"`
+	MoneyEnd        int            `json:"money_end"`
+	LevelStart      int            `json:"level_start"`
+	LevelEnd        int            `json:"level_end"`
+	Crashed         bool           `json:"crashed"`
+	Errors          []string       `json:"errors,omitempty"`
+}
+
+// Finish ends the session. crash is the panic that stopped the macro, if
+// any.
+func (s *Session) Finish(crash error) *Report {
+	if crash != nil {
+		s.Error(crash)
+	}
+	r := s.Snapshot()
+	r.Crashed = crash != nil
+	return r
+}
+
+// Snapshot summarizes the session so far.
+func (s *Session) Snapshot() *Report {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r := &Report{
+		Started:        s.started,
+		Ended:          time.Now(),
+		Cycles:         s.cycles,
+		SellsSucceeded: s.sellsOK,
+		SellsFailed:    s.sellsFailed,
+		OresGained:     make(map[string]int),
+		Errors:         append([]string(nil), s.errors...),
+	}
+	r.DurationSeconds = int(r.Ended.Sub(r.Started).Seconds())
+	if s.cycles > 0 {
+		r.AvgCycleSeconds = (s.cycleTime / time.Duration(s.cycles)).Seconds()
+	}
+
+	if s.first != nil {
+		r.MoneyStart, r.MoneyEnd = s.first.Money, s.last.Money
+		r.LevelStart, r.LevelEnd = s.first.Level, s.last.Level
+		for name, count := range s.last.LegendaryOres {
+			if gained := count - s.first.LegendaryOres[name]; gained > 0 {
+				r.OresGained[name] = gained
+			}
+		}
+	}
+	return r
+}
+
+func (r *Report) Duration() time.Duration {
+	return time.Duration(r.DurationSeconds) * time.Second
+}
+
+func (r *Report) MoneyDelta() int { return r.MoneyEnd - r.MoneyStart }
+func (r *Report) LevelDelta() int { return r.LevelEnd - r.LevelStart }
+
+// OreNames returns the gained ores sorted by name.
+func (r *Report) OreNames() []string {
+	names := make([]string, 0, len(r.OresGained))
+	for name := range r.OresGained {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Markdown renders the report for people.
+func (r *Report) Markdown() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Session %s\n\n", r.Started.Format("2006-01-02 15:04"))
+	if r.Crashed {
+		b.WriteString("**The macro crashed.**\n\n")
+	}
+	fmt.Fprintf(&b, "- Duration: %v\n", r.Duration())
+	fmt.Fprintf(&b, "- Cycles: %d (avg %.1fs)\n", r.Cycles, r.AvgCycleSeconds)
+	fmt.Fprintf(&b, "- Sells: %d succeeded, %d failed\n", r.SellsSucceeded, r.SellsFailed)
+	if r.MoneyEnd > 0 {
+		fmt.Fprintf(&b, "- Money: $%d → $%d (%+d)\n", r.MoneyStart, r.MoneyEnd, r.MoneyDelta())
+	}
+	if r.LevelEnd > 0 {
+		fmt.Fprintf(&b, "- Level: %d → %d (%+d)\n", r.LevelStart, r.LevelEnd, r.LevelDelta())
+	}
+
+	if len(r.OresGained) > 0 {
+		b.WriteString("\n## Legendary/Mythic ores gained\n\n")
+		for _, name := range r.OreNames() {
+			fmt.Fprintf(&b, "- %s: +%d\n", name, r.OresGained[name])
+		}
+	}
+	if len(r.Errors) > 0 {
+		b.WriteString("\n## Errors\n\n")
+		for _, e := range r.Errors {
+			fmt.Fprintf(&b, "- %s\n", e)
+		}
+	}
+	return b.String()
+}
+
+// Save writes the report as JSON and Markdown into dir and returns the
+// JSON path.
+func (r *Report) Save(dir string) (string, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	base := filepath.Join(dir, r.Started.Format("2006-01-02_150405"))
+
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(base+".json", data, 0644); err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(base+".md", []byte(r.Markdown()), 0644); err != nil {
+		return "", err
+	}
+	return base + ".json", nil
+}
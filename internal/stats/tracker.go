@@ -0,0 +1,126 @@
+package stats
+
+import (
+	"fmt"
+	"forger-companion/internal/data"
+	"forger-companion/internal/events"
+	"forger-companion/internal/ocr"
+	"maps"
+)
+
+// Tracker compares successive stats scans and publishes changes, ore finds,
+// level ups and money milestones.
+//
+// OCR misreads a number now and then, so a value only counts once two
+// scans in a row agree on it; a one-off misread never raises an event.
+// The first values counted only set the baseline for finds.
+type Tracker struct {
+	bus       *events.Bus
+	milestone int // publish each time money passes a multiple of this
+
+	last    *ocr.Stats // the previous scan, counted or not
+	counted *ocr.Stats // values two scans agreed on; nil before the baseline
+}
+
+func NewTracker(bus *events.Bus, milestone int) *Tracker {
+	return &Tracker{bus: bus, milestone: milestone}
+}
+
+// SetMilestone changes the money milestone step; 0 disables it.
+func (t *Tracker) SetMilestone(step int) {
+	t.milestone = step
+}
+
+// Reset forgets the baseline, e.g. when a new macro session starts.
+func (t *Tracker) Reset() {
+	t.last = nil
+	t.counted = nil
+}
+
+func (t *Tracker) Observe(cycle int, s *ocr.Stats) {
+	if s == nil {
+		return
+	}
+	prev := t.last
+	t.last = s
+	if prev == nil {
+		return
+	}
+
+	if t.counted == nil {
+		if s.Level != prev.Level || s.Money != prev.Money || !maps.Equal(s.LegendaryOres, prev.LegendaryOres) {
+			return // wait for two scans that agree on everything
+		}
+		t.counted = s
+		t.publishChange(cycle)
+		return
+	}
+
+	old := t.counted
+	next := &ocr.Stats{Level: old.Level, Money: old.Money, LegendaryOres: maps.Clone(old.LegendaryOres)}
+
+	for name, count := range s.LegendaryOres {
+		if prev.LegendaryOres[name] != count {
+			continue
+		}
+		if count > old.LegendaryOres[name] {
+			ore := data.Ores[name]
+			t.bus.Publish(events.Event{
+				Kind:    events.OreFound,
+				Message: fmt.Sprintf("Found %s %s (now %d)", ore.Rarity, name, count),
+				Cycle:   cycle,
+				Ore:     name,
+				Rarity:  ore.Rarity,
+				Count:   count,
+			})
+		}
+		next.LegendaryOres[name] = count
+	}
+	for name := range old.LegendaryOres {
+		_, now := s.LegendaryOres[name]
+		_, before := prev.LegendaryOres[name]
+		if !now && !before {
+			delete(next.LegendaryOres, name) // gone from two scans, e.g. sold
+		}
+	}
+
+	if s.Level == prev.Level {
+		if old.Level > 0 && s.Level > old.Level {
+			t.bus.Publish(events.Event{
+				Kind:    events.LevelUp,
+				Message: fmt.Sprintf("Reached level %d", s.Level),
+				Cycle:   cycle,
+				Level:   s.Level,
+			})
+		}
+		next.Level = s.Level
+	}
+
+	if s.Money == prev.Money {
+		if t.milestone > 0 && old.Money > 0 && s.Money/t.milestone > old.Money/t.milestone {
+			reached := s.Money / t.milestone * t.milestone
+			t.bus.Publish(events.Event{
+				Kind:    events.MoneyMilestone,
+				Message: fmt.Sprintf("Passed $%d", reached),
+				Cycle:   cycle,
+				Money:   s.Money,
+			})
+		}
+		next.Money = s.Money
+	}
+
+	t.counted = next
+	if next.Level != old.Level || next.Money != old.Money || !maps.Equal(next.LegendaryOres, old.LegendaryOres) {
+		t.publishChange(cycle)
+	}
+}
+
+// publishChange announces the counted stats.
+func (t *Tracker) publishChange(cycle int) {
+	t.bus.Publish(events.Event{
+		Kind:    events.StatsChanged,
+		Message: fmt.Sprintf("Level %d, $%d", t.counted.Level, t.counted.Money),
+		Cycle:   cycle,
+		Data:    t.counted,
+	})
+}
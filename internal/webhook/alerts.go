@@ -0,0 +1,147 @@
+package webhook
+
+import (
+	"fmt"
+	"forger-companion/internal/data"
+	"forger-companion/internal/events"
+	"forger-companion/internal/msgtemplate"
+	"forger-companion/internal/stats"
+	"log"
+	"time"
+)
+
+var alertStyles = map[events.Kind]struct {
+	title string
+	color int
+}{
+	events.MacroStarted:   {"▶️ Macro Started", 5793522},
+	events.MacroStopped:   {"⏹️ Macro Stopped", 9807270},
+	events.MacroCrashed:   {"💥 Macro Crashed", 15548997},
+	events.SellFailed:     {"⚠️ Sell Failed", 16705372},
+	events.GameWindowLost: {"🚫 Game Window Lost", 15548997},
+	events.OreFound:       {"🌟 Rare Ore Found", 16766720},
+	events.LevelUp:        {"📊 Level Up", 5763719},
+	events.MoneyMilestone: {"💰 Money Milestone", 5763719},
+	events.SessionReport:  {"📋 Session Report", 5793522},
+}
+
+// SendReport sends a session summary if the session_report rule allows it.
+func (m *Manager) SendReport(r *stats.Report) error {
+	webhook := m.settings().Webhook
+	rule := webhook.Events[string(events.SessionReport)]
+	if !webhook.Enabled || !rule.Enabled {
+		return nil
+	}
+
+	style := alertStyles[events.SessionReport]
+	msg := &Message{
+		Event:     string(events.SessionReport),
+		Title:     style.title,
+		Color:     style.color,
+		Footer:    "Forger Companion",
+		Timestamp: r.Ended,
+		Cycle:     r.Cycles,
+		Fields: []Field{
+			{Name: "⏱️ Duration", Value: r.Duration().String(), Inline: true},
+			{Name: "🔁 Cycles", Value: fmt.Sprintf("%d (avg %.1fs)", r.Cycles, r.AvgCycleSeconds), Inline: true},
+			{Name: "🛒 Sells", Value: fmt.Sprintf("%d ok, %d failed", r.SellsSucceeded, r.SellsFailed), Inline: true},
+		},
+	}
+	if r.Crashed {
+		msg.Description = "The macro crashed."
+		msg.Color = alertStyles[events.MacroCrashed].color
+	}
+	if r.MoneyEnd > 0 {
+		msg.Fields = append(msg.Fields, Field{Name: "💰 Money", Value: fmt.Sprintf("$%d (%+d)", r.MoneyEnd, r.MoneyDelta()), Inline: true})
+	}
+	if r.LevelEnd > 0 {
+		msg.Fields = append(msg.Fields, Field{Name: "📊 Level", Value: fmt.Sprintf("%d (%+d)", r.LevelEnd, r.LevelDelta()), Inline: true})
+	}
+	if len(r.OresGained) > 0 {
+		oresText := ""
+		for _, name := range r.OreNames() {
+			oresText += fmt.Sprintf("• %s: +%d\n", name, r.OresGained[name])
+		}
+		msg.Fields = append(msg.Fields, Field{Name: "🌟 Ores Gained", Value: oresText})
+	}
+	if len(r.Errors) > 0 {
+		msg.Fields = append(msg.Fields, Field{Name: "Errors", Value: fmt.Sprintf("%d (last: %s)", len(r.Errors), r.Errors[len(r.Errors)-1])})
+	}
+
+	d := m.templateData(string(events.SessionReport), r.Cycles)
+	d.Time = r.Ended
+	d.Session = msgtemplate.Session{Start: r.Started, Duration: r.Duration(), Cycles: r.Cycles}
+	if len(r.Errors) > 0 {
+		d.Error = r.Errors[len(r.Errors)-1]
+	}
+	d.SinceStart = &msgtemplate.Delta{
+		Ores:  r.OresGained,
+		Level: r.LevelDelta(),
+		Money: r.MoneyDelta(),
+		Over:  r.Duration(),
+	}
+	if r.MoneyEnd > 0 || r.LevelEnd > 0 {
+		d.Stats = &msgtemplate.Stats{Level: r.LevelEnd, Money: r.MoneyEnd}
+	}
+
+	return m.broadcastTo(rule.Notifiers, msg, d)
+}
+
+// HandleEvent sends an alert for e if its rule in webhook.events allows
+// it. Subscribe it to the event bus.
+func (m *Manager) HandleEvent(e events.Event) {
+	webhook := m.settings().Webhook
+	if !webhook.Enabled {
+		return
+	}
+	rule, ok := webhook.Events[string(e.Kind)]
+	if !ok || !rule.Enabled {
+		return
+	}
+	if rule.MinRarity != 0 && e.Kind == events.OreFound && !e.Rarity.AtLeast(rule.MinRarity) {
+		return
+	}
+
+	m.mu.Lock()
+	if last, ok := m.lastAlert[e.Kind]; ok && time.Since(last) < rule.Cooldown.Duration() {
+		m.mu.Unlock()
+		return
+	}
+	if m.lastAlert == nil {
+		m.lastAlert = make(map[events.Kind]time.Time)
+	}
+	m.lastAlert[e.Kind] = time.Now()
+	m.mu.Unlock()
+
+	d := m.templateData(string(e.Kind), e.Cycle)
+	d.Time = e.Time
+	d.Message = e.Message
+	d.Error = e.Error
+	if ore, ok := data.Ores[e.Ore]; ok {
+		d.Ore = &ore
+	}
+
+	if err := m.broadcastTo(rule.Notifiers, alertMessage(e), d); err != nil {
+		log.Printf("[Webhook] Alert %s: %v", e.Kind, err)
+	}
+}
+
+func alertMessage(e events.Event) *Message {
+	style := alertStyles[e.Kind]
+	msg := &Message{
+		Event:       string(e.Kind),
+		Title:       style.title,
+		Description: e.Message,
+		Color:       style.color,
+		Footer:      "Forger Companion",
+		Timestamp:   e.Time,
+		Cycle:       e.Cycle,
+	}
+	if e.Cycle > 0 {
+		msg.Fields = append(msg.Fields, Field{Name: "Cycle", Value: fmt.Sprintf("#%d", e.Cycle), Inline: true})
+	}
+	if e.Error != "" {
+		msg.Fields = append(msg.Fields, Field{Name: "Error", Value: e.Error})
+	}
+	return msg
+}
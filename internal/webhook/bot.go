@@ -0,0 +1,110 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"forger-companion/internal/config"
+	"log"
+	"mime/multipart"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const defaultBotAPI = "https://forger-production.up.railway.app"
+
+// bot asks the Forger Discord bot to DM the user. Requests are signed with
+// the license key when one is set; see docs/bot-api.md for the schema.
+type bot struct {
+	name       string
+	endpoint   string
+	discordID  config.Secret
+	licenseKey config.Secret
+}
+
+func newBot(cfg config.NotifierConfig) (Notifier, error) {
+	base := cfg.APIBase
+	if base == "" {
+		base = defaultBotAPI
+	}
+	if cfg.LicenseKey == "" {
+		log.Printf("[Webhook] %s: no license_key set, bot requests will be unsigned", cfg.Name)
+	}
+	return &bot{
+		name:       cfg.Name,
+		endpoint:   strings.TrimSuffix(base, "/") + "/api/progress",
+		discordID:  cfg.DiscordID,
+		licenseKey: cfg.LicenseKey,
+	}, nil
+}
+
+func (b *bot) Name() string { return b.name }
+
+func (b *bot) Send(ctx context.Context, msg *Message) error {
+	discordID, err := b.discordID.Value()
+	if err != nil {
+		return err
+	}
+	licenseKey, err := b.licenseKey.Value()
+	if err != nil {
+		return err
+	}
+
+	// Create multipart form
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+
+	if msg.Attachment != nil {
+		part, err := writer.CreateFormFile("image", msg.Attachment.Filename)
+		if err != nil {
+			return err
+		}
+		if _, err := part.Write(msg.Attachment.Data); err != nil {
+			return err
+		}
+	}
+
+	payload, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	writer.WriteField("discord_id", discordID)
+	writer.WriteField("event", msg.Event)
+	writer.WriteField("cycle", fmt.Sprintf("%d", msg.Cycle))
+	writer.WriteField("timestamp", msg.Timestamp.Format("2006-01-02T15:04:05Z07:00"))
+	writer.WriteField("message", string(payload))
+
+	writer.Close()
+
+	req, err := http.NewRequestWithContext(ctx, "POST", b.endpoint, bytes.NewReader(body.Bytes()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	if licenseKey != "" {
+		sign(req, licenseKey, body.Bytes(), time.Now())
+	}
+	return do(b.name, req)
+}
+
+// sign adds the headers the bot uses to authenticate a request and reject
+// replays: the Unix time it was sent and an HMAC-SHA256 over
+// "<timestamp>.<body>" keyed with the license key.
+func sign(req *http.Request, key string, body []byte, now time.Time) {
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+
+	mac := hmac.New(sha256.New, []byte(key))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+
+	req.Header.Set("X-Forger-Timestamp", timestamp)
+	req.Header.Set("X-Forger-Signature", "v1="+hex.EncodeToString(mac.Sum(nil)))
+}
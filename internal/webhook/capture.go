@@ -0,0 +1,189 @@
+package webhook
+
+import (
+	"bytes"
+	"fmt"
+	"forger-companion/internal/config"
+	"forger-companion/internal/game"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+	"log"
+	"time"
+
+	"github.com/kbinani/screenshot"
+)
+
+// captureAttachment returns the progress image to upload: an animated GIF
+// when SendGIF is on, otherwise a PNG or JPEG screenshot.
+func (m *Manager) captureAttachment(cycle int) (*Attachment, error) {
+	cfg := m.settings()
+	if cfg.Webhook.SendGIF {
+		duration := time.Duration(cfg.Webhook.GIFDuration) * time.Millisecond
+		data, err := m.captureGIF(cfg, cfg.Webhook.GIFFrames, duration)
+		if err != nil {
+			return nil, err
+		}
+		return &Attachment{
+			Filename:    fmt.Sprintf("progress_cycle_%d.gif", cycle),
+			ContentType: "image/gif",
+			Data:        data,
+		}, nil
+	}
+
+	img, err := m.captureScreen(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	capture := cfg.Webhook.Capture
+	buf := &bytes.Buffer{}
+	if capture.Format == "jpeg" {
+		if err := jpeg.Encode(buf, img, &jpeg.Options{Quality: capture.JPEGQuality}); err != nil {
+			return nil, err
+		}
+		return &Attachment{
+			Filename:    fmt.Sprintf("progress_cycle_%d.jpg", cycle),
+			ContentType: "image/jpeg",
+			Data:        buf.Bytes(),
+		}, nil
+	}
+
+	encoder := png.Encoder{CompressionLevel: png.BestCompression}
+	if err := encoder.Encode(buf, img); err != nil {
+		return nil, err
+	}
+	return &Attachment{
+		Filename:    fmt.Sprintf("progress_cycle_%d.png", cycle),
+		ContentType: "image/png",
+		Data:        buf.Bytes(),
+	}, nil
+}
+
+// captureScreen grabs the configured area, hides the masked parts and
+// scales the result down.
+func (m *Manager) captureScreen(cfg *config.Config) (image.Image, error) {
+	capture := cfg.Webhook.Capture
+	rect := captureRect(cfg)
+
+	img, err := screenshot.CaptureRect(rect)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, mask := range capture.Masks {
+		area := maskRect(cfg, mask).Sub(rect.Min).Intersect(img.Bounds())
+		if area.Empty() {
+			continue
+		}
+		if mask.Style == "blackout" {
+			draw.Draw(img, area, image.NewUniform(color.Black), image.Point{}, draw.Src)
+		} else {
+			blur(img, area)
+		}
+	}
+
+	width := int(float64(img.Bounds().Dx()) * capture.Scale)
+	height := int(float64(img.Bounds().Dy()) * capture.Scale)
+	if width < 1 || height < 1 || width >= img.Bounds().Dx() {
+		return img, nil
+	}
+	if capture.Resample == "bilinear" {
+		return bilinearResize(img, width, height), nil
+	}
+	return boxResize(img, width, height), nil
+}
+
+// captureRect is the screen area the capture mode asks for, falling back
+// to the primary display when the window or region can't be found.
+func captureRect(cfg *config.Config) image.Rectangle {
+	capture := cfg.Webhook.Capture
+	display := screenshot.GetDisplayBounds(0)
+
+	switch capture.Mode {
+	case "window":
+		if rect, ok := game.Window(); ok {
+			return rect
+		}
+		log.Println("[Webhook] Game window not found, capturing the full screen")
+	case "region":
+		if r := cfg.Regions[capture.Region]; r != nil {
+			return image.Rect(r.X, r.Y, r.X+r.Width, r.Y+r.Height)
+		}
+		log.Printf("[Webhook] Region %q not set, capturing the full screen", capture.Region)
+	}
+	return display
+}
+
+func maskRect(cfg *config.Config, mask config.Mask) image.Rectangle {
+	if mask.Region != "" {
+		if r := cfg.Regions[mask.Region]; r != nil {
+			return image.Rect(r.X, r.Y, r.X+r.Width, r.Y+r.Height)
+		}
+		return image.Rectangle{}
+	}
+	return image.Rect(mask.X, mask.Y, mask.X+mask.Width, mask.Y+mask.Height)
+}
+
+// blur makes area unreadable by shrinking it to a coarse grid of averages
+// and scaling that back up smoothly.
+func blur(img *image.RGBA, area image.Rectangle) {
+	const cell = 12 // source pixels per averaged block
+
+	small := boxResize(img.SubImage(area), max(area.Dx()/cell, 1), max(area.Dy()/cell, 1))
+	smooth := bilinearResize(small, area.Dx(), area.Dy())
+	draw.Draw(img, area, smooth, image.Point{}, draw.Src)
+}
+
+// bilinearResize scales img to width x height, interpolating between the
+// four nearest source pixels.
+func bilinearResize(img image.Image, width, height int) *image.RGBA {
+	src, ok := img.(*image.RGBA)
+	if !ok {
+		src = image.NewRGBA(img.Bounds())
+		draw.Draw(src, src.Bounds(), img, img.Bounds().Min, draw.Src)
+	}
+	b := src.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+
+	sx := float64(b.Dx()) / float64(width)
+	sy := float64(b.Dy()) / float64(height)
+	for y := 0; y < height; y++ {
+		fy := (float64(y)+0.5)*sy - 0.5
+		y0, wy := splitCoord(fy, b.Dy())
+		y1 := min(y0+1, b.Dy()-1)
+
+		for x := 0; x < width; x++ {
+			fx := (float64(x)+0.5)*sx - 0.5
+			x0, wx := splitCoord(fx, b.Dx())
+			x1 := min(x0+1, b.Dx()-1)
+
+			p00 := src.PixOffset(b.Min.X+x0, b.Min.Y+y0)
+			p10 := src.PixOffset(b.Min.X+x1, b.Min.Y+y0)
+			p01 := src.PixOffset(b.Min.X+x0, b.Min.Y+y1)
+			p11 := src.PixOffset(b.Min.X+x1, b.Min.Y+y1)
+			d := dst.PixOffset(x, y)
+			for c := 0; c < 4; c++ {
+				top := float64(src.Pix[p00+c])*(1-wx) + float64(src.Pix[p10+c])*wx
+				bottom := float64(src.Pix[p01+c])*(1-wx) + float64(src.Pix[p11+c])*wx
+				dst.Pix[d+c] = uint8(top*(1-wy) + bottom*wy + 0.5)
+			}
+		}
+	}
+	return dst
+}
+
+// splitCoord clamps a fractional source coordinate to [0, size-1] and
+// returns its integer part and fraction.
+func splitCoord(f float64, size int) (int, float64) {
+	if f < 0 {
+		return 0, 0
+	}
+	i := int(f)
+	if i >= size-1 {
+		return size - 1, 0
+	}
+	return i, f - float64(i)
+}
@@ -0,0 +1,76 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"forger-companion/internal/config"
+	"mime/multipart"
+	"net/http"
+)
+
+// discord posts embeds to a Discord channel webhook.
+type discord struct {
+	name string
+	url  config.Secret
+}
+
+func newDiscord(cfg config.NotifierConfig) (Notifier, error) {
+	return &discord{name: cfg.Name, url: cfg.URL}, nil
+}
+
+func (d *discord) Name() string { return d.name }
+
+func (d *discord) Send(ctx context.Context, msg *Message) error {
+	url, err := d.url.Value()
+	if err != nil {
+		return err
+	}
+
+	fields := make([]map[string]interface{}, 0, len(msg.Fields))
+	for _, f := range msg.Fields {
+		fields = append(fields, map[string]interface{}{"name": f.Name, "value": f.Value, "inline": f.Inline})
+	}
+	embed := map[string]interface{}{
+		"title":     msg.Title,
+		"color":     msg.Color,
+		"fields":    fields,
+		"timestamp": msg.Timestamp.Format("2006-01-02T15:04:05Z07:00"),
+	}
+	if msg.Description != "" {
+		embed["description"] = msg.Description
+	}
+	if msg.Footer != "" {
+		embed["footer"] = map[string]string{"text": msg.Footer}
+	}
+	if msg.Attachment != nil {
+		embed["image"] = map[string]string{"url": "attachment://" + msg.Attachment.Filename}
+	}
+
+	// Create multipart form
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+
+	if msg.Attachment != nil {
+		part, err := writer.CreateFormFile("file", msg.Attachment.Filename)
+		if err != nil {
+			return err
+		}
+		if _, err := part.Write(msg.Attachment.Data); err != nil {
+			return err
+		}
+	}
+
+	payload, _ := json.Marshal(map[string]interface{}{
+		"embeds": []interface{}{embed},
+	})
+	writer.WriteField("payload_json", string(payload))
+	writer.Close()
+
+	req, err := http.NewRequestWithContext(ctx, "POST", url, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	return do(d.name, req)
+}
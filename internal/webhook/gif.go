@@ -0,0 +1,236 @@
+package webhook
+
+import (
+	"bytes"
+	"errors"
+	"forger-companion/internal/config"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/gif"
+	"log"
+	"sort"
+	"time"
+)
+
+const (
+	// maxUploadBytes keeps attachments under Discord's upload limit for
+	// servers without boosts.
+	maxUploadBytes = 8 << 20
+
+	// maxGIFWidth caps frame width before quantizing; full-resolution GIFs
+	// are slow to dither and almost never fit the upload limit.
+	maxGIFWidth = 800
+)
+
+// captureGIF records frames evenly over duration and encodes them as an
+// animated GIF that fits in maxUploadBytes, shrinking the frames and then
+// dropping every other frame until it does.
+func (m *Manager) captureGIF(cfg *config.Config, frames int, duration time.Duration) ([]byte, error) {
+	if frames < 1 {
+		frames = 1
+	}
+	interval := duration / time.Duration(frames)
+
+	var captured []image.Image
+	for i := 0; i < frames; i++ {
+		if i > 0 {
+			time.Sleep(interval)
+		}
+		img, err := m.captureScreen(cfg)
+		if err != nil {
+			return nil, err
+		}
+		captured = append(captured, img)
+	}
+
+	width := captured[0].Bounds().Dx()
+	if width > maxGIFWidth {
+		width = maxGIFWidth
+	}
+	delay := int(interval / (10 * time.Millisecond)) // GIF delays are in 1/100s
+
+	for {
+		data, err := encodeGIF(captured, width, delay)
+		if err != nil {
+			return nil, err
+		}
+		if len(data) <= maxUploadBytes {
+			return data, nil
+		}
+
+		switch {
+		case width > 320:
+			width = width * 3 / 4
+		case len(captured) > 2:
+			captured = everyOther(captured)
+			delay *= 2
+		default:
+			return nil, errors.New("GIF does not fit the upload limit even at minimum size")
+		}
+		log.Printf("[Webhook] GIF is %d KB, retrying at %dpx with %d frames", len(data)/1024, width, len(captured))
+	}
+}
+
+func encodeGIF(frames []image.Image, width, delay int) ([]byte, error) {
+	scaled := make([]image.Image, len(frames))
+	for i, frame := range frames {
+		scaled[i] = resizeToWidth(frame, width)
+	}
+
+	palette := medianCutPalette(scaled, 256)
+	anim := &gif.GIF{}
+	for _, frame := range scaled {
+		paletted := image.NewPaletted(frame.Bounds(), palette)
+		draw.FloydSteinberg.Draw(paletted, frame.Bounds(), frame, image.Point{})
+		anim.Image = append(anim.Image, paletted)
+		anim.Delay = append(anim.Delay, delay)
+	}
+
+	buf := &bytes.Buffer{}
+	if err := gif.EncodeAll(buf, anim); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func everyOther(frames []image.Image) []image.Image {
+	kept := make([]image.Image, 0, (len(frames)+1)/2)
+	for i := 0; i < len(frames); i += 2 {
+		kept = append(kept, frames[i])
+	}
+	return kept
+}
+
+// resizeToWidth scales img down to width, keeping its aspect ratio, by
+// averaging each block of source pixels.
+func resizeToWidth(img image.Image, width int) image.Image {
+	b := img.Bounds()
+	if b.Dx() <= width {
+		return img
+	}
+	height := b.Dy() * width / b.Dx()
+	return boxResize(img, width, height)
+}
+
+func boxResize(img image.Image, width, height int) *image.RGBA {
+	b := img.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+
+	for y := 0; y < height; y++ {
+		y0 := b.Min.Y + y*b.Dy()/height
+		y1 := b.Min.Y + (y+1)*b.Dy()/height
+		if y1 == y0 {
+			y1 = y0 + 1
+		}
+		for x := 0; x < width; x++ {
+			x0 := b.Min.X + x*b.Dx()/width
+			x1 := b.Min.X + (x+1)*b.Dx()/width
+			if x1 == x0 {
+				x1 = x0 + 1
+			}
+
+			var r, g, bl, a, n uint32
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					cr, cg, cb, ca := img.At(sx, sy).RGBA()
+					r, g, bl, a = r+cr, g+cg, bl+cb, a+ca
+					n++
+				}
+			}
+			dst.SetRGBA(x, y, color.RGBA{
+				R: uint8(r / n >> 8), G: uint8(g / n >> 8), B: uint8(bl / n >> 8), A: uint8(a / n >> 8),
+			})
+		}
+	}
+	return dst
+}
+
+// medianCutPalette builds a palette shared by all frames, so colors don't
+// flicker between frames, by repeatedly splitting the sampled colors along
+// their widest channel.
+func medianCutPalette(frames []image.Image, size int) color.Palette {
+	const maxSamples = 60000
+
+	total := 0
+	for _, frame := range frames {
+		total += frame.Bounds().Dx() * frame.Bounds().Dy()
+	}
+	step := total/maxSamples + 1
+
+	var samples [][3]uint8
+	i := 0
+	for _, frame := range frames {
+		b := frame.Bounds()
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			for x := b.Min.X; x < b.Max.X; x++ {
+				if i%step == 0 {
+					r, g, bl, _ := frame.At(x, y).RGBA()
+					samples = append(samples, [3]uint8{uint8(r >> 8), uint8(g >> 8), uint8(bl >> 8)})
+				}
+				i++
+			}
+		}
+	}
+
+	boxes := [][][3]uint8{samples}
+	for len(boxes) < size {
+		// Split the box with the widest channel range
+		best, bestChannel, bestRange := -1, 0, 0
+		for i, box := range boxes {
+			if len(box) < 2 {
+				continue
+			}
+			channel, rng := widestChannel(box)
+			if rng > bestRange {
+				best, bestChannel, bestRange = i, channel, rng
+			}
+		}
+		if best < 0 {
+			break
+		}
+
+		box := boxes[best]
+		sort.Slice(box, func(a, b int) bool { return box[a][bestChannel] < box[b][bestChannel] })
+		mid := len(box) / 2
+		boxes[best] = box[:mid]
+		boxes = append(boxes, box[mid:])
+	}
+
+	palette := make(color.Palette, 0, len(boxes))
+	for _, box := range boxes {
+		var r, g, b int
+		for _, c := range box {
+			r, g, b = r+int(c[0]), g+int(c[1]), b+int(c[2])
+		}
+		n := len(box)
+		if n == 0 {
+			continue
+		}
+		palette = append(palette, color.RGBA{R: uint8(r / n), G: uint8(g / n), B: uint8(b / n), A: 255})
+	}
+	return palette
+}
+
+func widestChannel(box [][3]uint8) (int, int) {
+	lo := [3]uint8{255, 255, 255}
+	hi := [3]uint8{}
+	for _, c := range box {
+		for ch := 0; ch < 3; ch++ {
+			if c[ch] < lo[ch] {
+				lo[ch] = c[ch]
+			}
+			if c[ch] > hi[ch] {
+				hi[ch] = c[ch]
+			}
+		}
+	}
+
+	best, bestRange := 0, -1
+	for ch := 0; ch < 3; ch++ {
+		if rng := int(hi[ch]) - int(lo[ch]); rng > bestRange {
+			best, bestRange = ch, rng
+		}
+	}
+	return best, bestRange
+}
@@ -0,0 +1,62 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"forger-companion/internal/config"
+	"forger-companion/internal/msgtemplate"
+	"net/http"
+	"text/template"
+)
+
+// jsonPost sends the message to any HTTP endpoint as JSON, either the
+// Message itself or the output of a user-defined template.
+type jsonPost struct {
+	name     string
+	url      config.Secret
+	headers  map[string]string
+	template *template.Template
+}
+
+func newJSONPost(cfg config.NotifierConfig) (Notifier, error) {
+	j := &jsonPost{name: cfg.Name, url: cfg.URL, headers: cfg.Headers}
+	if cfg.Template != "" {
+		tmpl, err := template.New(cfg.Name).Funcs(msgtemplate.Funcs).Parse(cfg.Template)
+		if err != nil {
+			return nil, err
+		}
+		j.template = tmpl
+	}
+	return j, nil
+}
+
+func (j *jsonPost) Name() string { return j.name }
+
+func (j *jsonPost) Send(ctx context.Context, msg *Message) error {
+	url, err := j.url.Value()
+	if err != nil {
+		return err
+	}
+
+	var body []byte
+	if j.template != nil {
+		buf := &bytes.Buffer{}
+		if err := j.template.Execute(buf, msg); err != nil {
+			return err
+		}
+		body = buf.Bytes()
+	} else {
+		body, _ = json.Marshal(msg)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range j.headers {
+		req.Header.Set(k, v)
+	}
+	return do(j.name, req)
+}
@@ -0,0 +1,206 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"forger-companion/internal/config"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Message is a notification in backend-neutral form. Each Notifier renders
+// it in its own format.
+type Message struct {
+	Event       string    `json:"event"`
+	Title       string    `json:"title"`
+	Description string    `json:"description,omitempty"`
+	Color       int       `json:"color"`
+	Fields      []Field   `json:"fields,omitempty"`
+	Footer      string    `json:"footer,omitempty"`
+	Timestamp   time.Time `json:"timestamp"`
+	Cycle       int       `json:"cycle,omitempty"`
+
+	Attachment *Attachment `json:"-"`
+}
+
+type Field struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Inline bool   `json:"inline,omitempty"`
+}
+
+type Attachment struct {
+	Filename    string
+	ContentType string
+	Data        []byte
+}
+
+// Text renders the message as plain text for backends without rich
+// formatting.
+func (msg *Message) Text() string {
+	var b strings.Builder
+	if msg.Description != "" {
+		b.WriteString(msg.Description)
+		b.WriteString("\n")
+	}
+	for _, f := range msg.Fields {
+		fmt.Fprintf(&b, "%s: %s\n", f.Name, strings.TrimRight(f.Value, "\n"))
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+type Notifier interface {
+	Name() string
+	Send(ctx context.Context, msg *Message) error
+}
+
+// Factory builds a Notifier from its config entry.
+type Factory func(cfg config.NotifierConfig) (Notifier, error)
+
+var (
+	registryMu sync.RWMutex
+	registry   = make(map[string]Factory)
+)
+
+// Register makes a notifier type available under kind.
+func Register(kind string, factory Factory) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	registry[kind] = factory
+}
+
+// Kinds lists the registered notifier types.
+func Kinds() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	kinds := make([]string, 0, len(registry))
+	for kind := range registry {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+	return kinds
+}
+
+// NewNotifier builds the notifier described by cfg.
+func NewNotifier(cfg config.NotifierConfig) (Notifier, error) {
+	registryMu.RLock()
+	factory, ok := registry[cfg.Type]
+	registryMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("notifier %q: unknown type %q", cfg.Name, cfg.Type)
+	}
+	n, err := factory(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("notifier %q: %w", cfg.Name, err)
+	}
+	return n, nil
+}
+
+func init() {
+	Register("discord", newDiscord)
+	Register("bot", newBot)
+	Register("slack", newSlack)
+	Register("telegram", newTelegram)
+	Register("ntfy", newNtfy)
+	Register("json", newJSONPost)
+}
+
+// httpClient is shared by every backend.
+var httpClient = &http.Client{Timeout: 30 * time.Second}
+
+// StatusError is returned when a backend answers with a non-2xx status.
+// RetryAfter is set when the backend said how long to wait.
+type StatusError struct {
+	Notifier   string
+	StatusCode int
+	Body       string
+	RetryAfter time.Duration
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("%s failed: %d %s", e.Notifier, e.StatusCode, e.Body)
+}
+
+// do sends req and turns a non-2xx answer into a StatusError.
+func do(name string, req *http.Request) error {
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return &StatusError{
+			Notifier:   name,
+			StatusCode: resp.StatusCode,
+			Body:       strings.TrimSpace(string(body)),
+			RetryAfter: retryAfter(resp, body),
+		}
+	}
+
+	// Back off before the bucket runs dry rather than after
+	if resp.Header.Get("X-RateLimit-Remaining") == "0" {
+		if wait := parseSeconds(resp.Header.Get("X-RateLimit-Reset-After")); wait > 0 {
+			return &rateLimited{wait: wait}
+		}
+	}
+	return nil
+}
+
+// rateLimited reports a successful send that used up the rate limit bucket.
+// The queue treats it as delivered but holds further sends.
+type rateLimited struct {
+	wait time.Duration
+}
+
+func (r *rateLimited) Error() string {
+	return fmt.Sprintf("rate limit reached, next send in %v", r.wait)
+}
+
+// retryAfter reads how long a rate limited or unavailable backend asked us
+// to wait: the Retry-After header, Discord's X-RateLimit-Reset-After, or a
+// retry_after field in the JSON body (Discord, Telegram).
+func retryAfter(resp *http.Response, body []byte) time.Duration {
+	if v := resp.Header.Get("Retry-After"); v != "" {
+		if wait := parseSeconds(v); wait > 0 {
+			return wait
+		}
+		if t, err := http.ParseTime(v); err == nil {
+			return time.Until(t)
+		}
+	}
+	if wait := parseSeconds(resp.Header.Get("X-RateLimit-Reset-After")); wait > 0 && resp.StatusCode == 429 {
+		return wait
+	}
+
+	var payload struct {
+		RetryAfter float64 `json:"retry_after"`
+		Parameters struct {
+			RetryAfter float64 `json:"retry_after"`
+		} `json:"parameters"`
+	}
+	if json.Unmarshal(body, &payload) == nil {
+		if payload.RetryAfter > 0 {
+			return time.Duration(payload.RetryAfter * float64(time.Second))
+		}
+		if payload.Parameters.RetryAfter > 0 {
+			return time.Duration(payload.Parameters.RetryAfter * float64(time.Second))
+		}
+	}
+	return 0
+}
+
+func parseSeconds(s string) time.Duration {
+	secs, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	if err != nil || secs <= 0 {
+		return 0
+	}
+	return time.Duration(secs * float64(time.Second))
+}
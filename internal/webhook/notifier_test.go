@@ -0,0 +1,397 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"forger-companion/internal/config"
+	"html"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// request is what a test server received.
+type request struct {
+	method string
+	path   string
+	header http.Header
+	body   []byte
+}
+
+// form parses a multipart body into its fields and files.
+func (r request) form(t *testing.T) (fields map[string]string, files map[string]*multipart.Part, data map[string][]byte) {
+	t.Helper()
+	mediaType, params, err := mime.ParseMediaType(r.header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/form-data" {
+		t.Fatalf("content type %q isn't multipart", r.header.Get("Content-Type"))
+	}
+	fields = make(map[string]string)
+	files = make(map[string]*multipart.Part)
+	data = make(map[string][]byte)
+	reader := multipart.NewReader(bytes.NewReader(r.body), params["boundary"])
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			return fields, files, data
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		value, _ := io.ReadAll(part)
+		if part.FileName() != "" {
+			files[part.FormName()] = part
+			data[part.FormName()] = value
+		} else {
+			fields[part.FormName()] = string(value)
+		}
+	}
+}
+
+// serve starts a server answering with status and header, and returns the
+// requests it gets.
+func serve(t *testing.T, status int, header map[string]string) (*httptest.Server, *[]request) {
+	t.Helper()
+	var got []request
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		got = append(got, request{method: r.Method, path: r.URL.Path, header: r.Header.Clone(), body: body})
+		for k, v := range header {
+			w.Header().Set(k, v)
+		}
+		w.WriteHeader(status)
+		io.WriteString(w, `{"ok": false, "description": "test"}`)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &got
+}
+
+func testMessage(attachment *Attachment) *Message {
+	return &Message{
+		Event:       "progress",
+		Title:       "Progress <1>",
+		Description: "Cycle 3 & counting",
+		Color:       0x00ff00,
+		Fields:      []Field{{Name: "Money", Value: "$1,000", Inline: true}},
+		Footer:      "Forger Companion",
+		Timestamp:   time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+		Cycle:       3,
+		Attachment:  attachment,
+	}
+}
+
+var testPNG = &Attachment{Filename: "progress.png", ContentType: "image/png", Data: []byte("\x89PNG fake image")}
+
+func newTestNotifier(t *testing.T, cfg config.NotifierConfig) Notifier {
+	t.Helper()
+	if cfg.Name == "" {
+		cfg.Name = cfg.Type
+	}
+	n, err := NewNotifier(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+func TestDiscordPayload(t *testing.T) {
+	srv, got := serve(t, http.StatusNoContent, nil)
+	n := newTestNotifier(t, config.NotifierConfig{Type: "discord", URL: config.Secret(srv.URL + "/api/webhooks/1/abc")})
+
+	if err := n.Send(context.Background(), testMessage(testPNG)); err != nil {
+		t.Fatal(err)
+	}
+	if len(*got) != 1 {
+		t.Fatalf("got %d requests", len(*got))
+	}
+	r := (*got)[0]
+	if r.method != "POST" || r.path != "/api/webhooks/1/abc" {
+		t.Errorf("%s %s", r.method, r.path)
+	}
+
+	fields, files, data := r.form(t)
+	if files["file"] == nil || files["file"].FileName() != "progress.png" || !bytes.Equal(data["file"], testPNG.Data) {
+		t.Errorf("file part = %v, %q", files["file"], data["file"])
+	}
+	var payload struct {
+		Embeds []struct {
+			Title       string `json:"title"`
+			Description string `json:"description"`
+			Color       int    `json:"color"`
+			Timestamp   string `json:"timestamp"`
+			Fields      []struct {
+				Name   string `json:"name"`
+				Value  string `json:"value"`
+				Inline bool   `json:"inline"`
+			} `json:"fields"`
+			Footer struct {
+				Text string `json:"text"`
+			} `json:"footer"`
+			Image struct {
+				URL string `json:"url"`
+			} `json:"image"`
+		} `json:"embeds"`
+	}
+	if err := json.Unmarshal([]byte(fields["payload_json"]), &payload); err != nil {
+		t.Fatal(err)
+	}
+	if len(payload.Embeds) != 1 {
+		t.Fatalf("got %d embeds", len(payload.Embeds))
+	}
+	e := payload.Embeds[0]
+	if e.Title != "Progress <1>" || e.Description != "Cycle 3 & counting" || e.Color != 0x00ff00 || e.Footer.Text != "Forger Companion" {
+		t.Errorf("embed = %+v", e)
+	}
+	if e.Timestamp != "2026-01-02T03:04:05Z" {
+		t.Errorf("timestamp = %q", e.Timestamp)
+	}
+	if len(e.Fields) != 1 || e.Fields[0].Name != "Money" || e.Fields[0].Value != "$1,000" || !e.Fields[0].Inline {
+		t.Errorf("fields = %+v", e.Fields)
+	}
+	if e.Image.URL != "attachment://progress.png" {
+		t.Errorf("image = %q", e.Image.URL)
+	}
+}
+
+func TestSlackPayload(t *testing.T) {
+	srv, got := serve(t, http.StatusOK, nil)
+	n := newTestNotifier(t, config.NotifierConfig{Type: "slack", URL: config.Secret(srv.URL + "/services/T/B/x")})
+
+	msg := testMessage(testPNG)
+	for i := 0; i < 11; i++ {
+		msg.Fields = append(msg.Fields, Field{Name: "Ore", Value: "x1"})
+	}
+	if err := n.Send(context.Background(), msg); err != nil {
+		t.Fatal(err)
+	}
+	r := (*got)[0]
+	if ct := r.header.Get("Content-Type"); ct != "application/json" {
+		t.Errorf("content type = %q; slack webhooks can't take uploads", ct)
+	}
+	var payload struct {
+		Text   string `json:"text"`
+		Blocks []struct {
+			Type   string              `json:"type"`
+			Text   map[string]string   `json:"text"`
+			Fields []map[string]string `json:"fields"`
+		} `json:"blocks"`
+	}
+	if err := json.Unmarshal(r.body, &payload); err != nil {
+		t.Fatal(err)
+	}
+	if payload.Text != msg.Title {
+		t.Errorf("fallback text = %q", payload.Text)
+	}
+	var kinds []string
+	fields := 0
+	for _, b := range payload.Blocks {
+		kinds = append(kinds, b.Type)
+		if len(b.Fields) > 10 {
+			t.Errorf("section has %d fields, slack allows 10", len(b.Fields))
+		}
+		fields += len(b.Fields)
+	}
+	if got, want := strings.Join(kinds, ","), "header,section,section,section,context"; got != want {
+		t.Errorf("blocks = %s, want %s", got, want)
+	}
+	if fields != 12 {
+		t.Errorf("sent %d fields, want 12", fields)
+	}
+	if payload.Blocks[2].Fields[0]["text"] != "*Money*\n$1,000" {
+		t.Errorf("field = %q", payload.Blocks[2].Fields[0]["text"])
+	}
+}
+
+func TestTelegramPayload(t *testing.T) {
+	srv, got := serve(t, http.StatusOK, nil)
+	n := newTestNotifier(t, config.NotifierConfig{Type: "telegram", Token: "123:abc", ChatID: "42", APIBase: srv.URL + "/"})
+
+	if err := n.Send(context.Background(), testMessage(nil)); err != nil {
+		t.Fatal(err)
+	}
+	gif := &Attachment{Filename: "progress.gif", ContentType: "image/gif", Data: []byte("GIF89a fake")}
+	for _, a := range []*Attachment{testPNG, gif} {
+		if err := n.Send(context.Background(), testMessage(a)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(*got) != 3 {
+		t.Fatalf("got %d requests", len(*got))
+	}
+
+	text := (*got)[0]
+	if text.path != "/bot123:abc/sendMessage" {
+		t.Errorf("path = %s", text.path)
+	}
+	var payload map[string]string
+	if err := json.Unmarshal(text.body, &payload); err != nil {
+		t.Fatal(err)
+	}
+	want := "<b>Progress &lt;1&gt;</b>\nCycle 3 &amp; counting\n<b>Money:</b> $1,000"
+	if payload["chat_id"] != "42" || payload["parse_mode"] != "HTML" || payload["text"] != want {
+		t.Errorf("payload = %q", payload)
+	}
+
+	for i, tc := range []struct{ path, field string }{{"/bot123:abc/sendPhoto", "photo"}, {"/bot123:abc/sendAnimation", "animation"}} {
+		r := (*got)[i+1]
+		if r.path != tc.path {
+			t.Errorf("path = %s, want %s", r.path, tc.path)
+		}
+		fields, files, data := r.form(t)
+		if fields["chat_id"] != "42" || fields["caption"] != want {
+			t.Errorf("fields = %q", fields)
+		}
+		a := []*Attachment{testPNG, gif}[i]
+		if files[tc.field] == nil || files[tc.field].FileName() != a.Filename || !bytes.Equal(data[tc.field], a.Data) {
+			t.Errorf("%s part = %q", tc.field, data[tc.field])
+		}
+	}
+}
+
+func TestTelegramCaptionLimit(t *testing.T) {
+	srv, got := serve(t, http.StatusOK, nil)
+	n := newTestNotifier(t, config.NotifierConfig{Type: "telegram", Token: "1:a", ChatID: "42", APIBase: srv.URL})
+
+	msg := testMessage(testPNG)
+	for i := 0; i < 100; i++ {
+		msg.Fields = append(msg.Fields, Field{Name: "Ore <b>", Value: "x1 & more"})
+	}
+	if err := n.Send(context.Background(), msg); err != nil {
+		t.Fatal(err)
+	}
+	fields, _, _ := (*got)[0].form(t)
+	caption := fields["caption"]
+
+	if strings.Count(caption, "<b>") != strings.Count(caption, "</b>") {
+		t.Errorf("unbalanced tags in %q", caption)
+	}
+	visible := html.UnescapeString(strings.NewReplacer("<b>", "", "</b>", "").Replace(caption))
+	if n := len([]rune(visible)); n != telegramCaptionLimit {
+		t.Errorf("caption shows %d characters, want %d", n, telegramCaptionLimit)
+	}
+	if !strings.HasSuffix(visible, "…") {
+		t.Errorf("cut caption doesn't end with an ellipsis: %q", visible[len(visible)-20:])
+	}
+	if i := strings.LastIndex(caption, "&"); i >= 0 && !strings.Contains(caption[i:], ";") {
+		t.Errorf("caption ends inside an entity: %q", caption[i:])
+	}
+}
+
+func TestNtfyPayload(t *testing.T) {
+	srv, got := serve(t, http.StatusOK, nil)
+	n := newTestNotifier(t, config.NotifierConfig{Type: "ntfy", URL: config.Secret(srv.URL + "/forge"), Token: "tk_1"})
+
+	if err := n.Send(context.Background(), testMessage(nil)); err != nil {
+		t.Fatal(err)
+	}
+	if err := n.Send(context.Background(), testMessage(testPNG)); err != nil {
+		t.Fatal(err)
+	}
+
+	text, file := (*got)[0], (*got)[1]
+	if text.method != "POST" || string(text.body) != "Cycle 3 & counting\nMoney: $1,000" {
+		t.Errorf("%s %q", text.method, text.body)
+	}
+	if text.header.Get("Title") != "Progress <1>" || text.header.Get("Authorization") != "Bearer tk_1" {
+		t.Errorf("headers = %v", text.header)
+	}
+	if file.method != "PUT" || !bytes.Equal(file.body, testPNG.Data) {
+		t.Errorf("%s %q", file.method, file.body)
+	}
+	if file.header.Get("Filename") != "progress.png" || file.header.Get("Message") != `Cycle 3 & counting\nMoney: $1,000` {
+		t.Errorf("headers = %v", file.header)
+	}
+}
+
+func TestJSONPostPayload(t *testing.T) {
+	srv, got := serve(t, http.StatusOK, nil)
+	n := newTestNotifier(t, config.NotifierConfig{Type: "json", URL: config.Secret(srv.URL), Headers: map[string]string{"X-Key": "k"}})
+	if err := n.Send(context.Background(), testMessage(testPNG)); err != nil {
+		t.Fatal(err)
+	}
+	r := (*got)[0]
+	if r.header.Get("X-Key") != "k" || r.header.Get("Content-Type") != "application/json" {
+		t.Errorf("headers = %v", r.header)
+	}
+	var payload map[string]interface{}
+	if err := json.Unmarshal(r.body, &payload); err != nil {
+		t.Fatal(err)
+	}
+	if payload["title"] != "Progress <1>" || payload["cycle"] != 3.0 || payload["event"] != "progress" {
+		t.Errorf("payload = %v", payload)
+	}
+	if _, ok := payload["attachment"]; ok {
+		t.Error("attachment was sent in the JSON body")
+	}
+
+	templated := newTestNotifier(t, config.NotifierConfig{Type: "json", URL: config.Secret(srv.URL), Template: `{"text": {{json .Title}}}`})
+	if err := templated.Send(context.Background(), testMessage(nil)); err != nil {
+		t.Fatal(err)
+	}
+	if body := string((*got)[1].body); body != `{"text": "Progress \u003c1\u003e"}` {
+		t.Errorf("templated body = %s", body)
+	}
+}
+
+func TestStatusHandling(t *testing.T) {
+	tests := []struct {
+		status     int
+		header     map[string]string
+		retryable  bool
+		retryAfter time.Duration
+	}{
+		{http.StatusInternalServerError, nil, true, 0},
+		{http.StatusBadGateway, nil, true, 0},
+		{http.StatusRequestTimeout, nil, true, 0},
+		{http.StatusTooManyRequests, map[string]string{"Retry-After": "7"}, true, 7 * time.Second},
+		{http.StatusBadRequest, nil, false, 0},
+		{http.StatusUnauthorized, nil, false, 0},
+		{http.StatusNotFound, nil, false, 0},
+	}
+	for _, kind := range []string{"discord", "slack", "telegram", "ntfy", "json"} {
+		for _, tc := range tests {
+			srv, _ := serve(t, tc.status, tc.header)
+			cfg := config.NotifierConfig{Type: kind, URL: config.Secret(srv.URL), Token: "1:a", ChatID: "1", APIBase: srv.URL}
+			err := newTestNotifier(t, cfg).Send(context.Background(), testMessage(nil))
+
+			var statusErr *StatusError
+			if !errors.As(err, &statusErr) || statusErr.StatusCode != tc.status {
+				t.Errorf("%s %d: err = %v", kind, tc.status, err)
+				continue
+			}
+			if retryable(err) != tc.retryable {
+				t.Errorf("%s %d: retryable = %v, want %v", kind, tc.status, !tc.retryable, tc.retryable)
+			}
+			if statusErr.RetryAfter != tc.retryAfter {
+				t.Errorf("%s %d: retry after %v, want %v", kind, tc.status, statusErr.RetryAfter, tc.retryAfter)
+			}
+		}
+	}
+}
+
+func TestNetworkErrorsRetry(t *testing.T) {
+	srv, _ := serve(t, http.StatusOK, nil)
+	url := srv.URL
+	srv.Close()
+
+	err := newTestNotifier(t, config.NotifierConfig{Type: "discord", URL: config.Secret(url + "/api/webhooks/1/secret")}).
+		Send(context.Background(), testMessage(nil))
+	if err == nil || !retryable(err) {
+		t.Errorf("refused connection: err = %v, retryable = %v", err, retryable(err))
+	}
+	if redacted := redactURL(err).Error(); strings.Contains(redacted, "secret") {
+		t.Errorf("redacted error still has the URL path: %s", redacted)
+	}
+
+	err = newTestNotifier(t, config.NotifierConfig{Type: "discord", URL: "ftp://example.com/hook"}).
+		Send(context.Background(), testMessage(nil))
+	if err == nil || retryable(err) {
+		t.Errorf("unsupported scheme: err = %v, want a permanent error", err)
+	}
+}
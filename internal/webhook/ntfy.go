@@ -0,0 +1,57 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"forger-companion/internal/config"
+	"net/http"
+	"strings"
+)
+
+// ntfy publishes to an ntfy topic URL such as https://ntfy.sh/my-topic.
+// Attachments are uploaded as the request body with the text in a header.
+type ntfy struct {
+	name  string
+	url   config.Secret
+	token config.Secret
+}
+
+func newNtfy(cfg config.NotifierConfig) (Notifier, error) {
+	return &ntfy{name: cfg.Name, url: cfg.URL, token: cfg.Token}, nil
+}
+
+func (n *ntfy) Name() string { return n.name }
+
+func (n *ntfy) Send(ctx context.Context, msg *Message) error {
+	url, err := n.url.Value()
+	if err != nil {
+		return err
+	}
+
+	text := msg.Text()
+	var req *http.Request
+	if msg.Attachment != nil {
+		req, err = http.NewRequestWithContext(ctx, "PUT", url, bytes.NewReader(msg.Attachment.Data))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Filename", msg.Attachment.Filename)
+		// Headers can't hold newlines; ntfy turns "\n" back into line breaks
+		req.Header.Set("Message", strings.ReplaceAll(text, "\n", `\n`))
+	} else {
+		req, err = http.NewRequestWithContext(ctx, "POST", url, strings.NewReader(text))
+		if err != nil {
+			return err
+		}
+	}
+	req.Header.Set("Title", msg.Title)
+
+	if n.token != "" {
+		token, err := n.token.Value()
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	return do(n.name, req)
+}
@@ -0,0 +1,194 @@
+package webhook
+
+import (
+	"encoding/json"
+	"fmt"
+	"forger-companion/internal/msgtemplate"
+	"forger-companion/internal/ocr"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// sessionResume is how long after the last update a restarted macro still
+// continues the previous session instead of starting a new one.
+const sessionResume = 30 * time.Minute
+
+type snapshot struct {
+	Time  time.Time      `json:"time"`
+	Ores  map[string]int `json:"ores"`
+	Level int            `json:"level"`
+	Money int            `json:"money"`
+}
+
+func newSnapshot(stats *ocr.Stats) snapshot {
+	ores := make(map[string]int, len(stats.LegendaryOres))
+	for name, count := range stats.LegendaryOres {
+		ores[name] = count
+	}
+	return snapshot{Time: time.Now(), Ores: ores, Level: stats.Level, Money: stats.Money}
+}
+
+// progress remembers the stats at session start and at the last update so
+// updates can show what changed. It is saved to disk so a restart within a
+// session keeps the baseline.
+type progress struct {
+	path string
+	mu   sync.Mutex // the macro records while templates read the start
+
+	Start *snapshot `json:"start"`
+	Last  *snapshot `json:"last"`
+}
+
+// delta is the change between two snapshots.
+type delta struct {
+	Ores  map[string]int
+	Level int
+	Money int
+	Over  time.Duration
+}
+
+func loadProgress(path string) *progress {
+	p := &progress{path: path}
+	if raw, err := os.ReadFile(path); err == nil {
+		json.Unmarshal(raw, p)
+	}
+	return p
+}
+
+// begin starts a new session unless the last update was recent enough to
+// continue the previous one.
+func (p *progress) begin() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.Last != nil && time.Since(p.Last.Time) < sessionResume {
+		return
+	}
+	p.Start, p.Last = nil, nil
+}
+
+// record stores stats as the latest snapshot and returns the changes since
+// the previous update and since the session started. Either is nil if there
+// was no earlier snapshot.
+func (p *progress) record(stats *ocr.Stats) (sinceLast, sinceStart *delta) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	now := newSnapshot(stats)
+	if p.Last != nil {
+		sinceLast = diff(*p.Last, now)
+	}
+	if p.Start != nil {
+		sinceStart = diff(*p.Start, now)
+	} else {
+		start := now
+		p.Start = &start
+	}
+	p.Last = &now
+	p.save()
+	return sinceLast, sinceStart
+}
+
+// started returns when the session started, or false if no stats have
+// been recorded yet.
+func (p *progress) started() (time.Time, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.Start == nil {
+		return time.Time{}, false
+	}
+	return p.Start.Time, true
+}
+
+func (p *progress) save() {
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return
+	}
+	os.MkdirAll(filepath.Dir(p.path), 0755)
+	os.WriteFile(p.path, data, 0644)
+}
+
+func diff(from, to snapshot) *delta {
+	d := &delta{Ores: make(map[string]int), Over: to.Time.Sub(from.Time)}
+	for name, count := range to.Ores {
+		if n := count - from.Ores[name]; n != 0 {
+			d.Ores[name] = n
+		}
+	}
+	// A failed read shows up as 0; don't report it as a loss
+	if from.Level > 0 && to.Level > 0 {
+		d.Level = to.Level - from.Level
+	}
+	if from.Money > 0 && to.Money > 0 {
+		d.Money = to.Money - from.Money
+	}
+	return d
+}
+
+func (d *delta) oreTotal() int {
+	total := 0
+	for _, n := range d.Ores {
+		if n > 0 {
+			total += n
+		}
+	}
+	return total
+}
+
+// String renders the delta as "+3 Mythril, +$1.2M, +2 levels".
+func (d *delta) String() string {
+	var parts []string
+	names := make([]string, 0, len(d.Ores))
+	for name := range d.Ores {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		parts = append(parts, fmt.Sprintf("%+d %s", d.Ores[name], strings.TrimSuffix(name, " Ore")))
+	}
+	if d.Money != 0 {
+		sign := "+"
+		if d.Money < 0 {
+			sign = "-"
+		}
+		parts = append(parts, sign+msgtemplate.Money(abs(d.Money)))
+	}
+	if d.Level != 0 {
+		unit := "levels"
+		if d.Level == 1 || d.Level == -1 {
+			unit = "level"
+		}
+		parts = append(parts, fmt.Sprintf("%+d %s", d.Level, unit))
+	}
+	if len(parts) == 0 {
+		return "No change"
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Rates renders per-hour rates for money, ores and levels.
+func (d *delta) Rates() string {
+	hours := d.Over.Hours()
+	if hours < 1.0/60 {
+		return ""
+	}
+	var parts []string
+	if d.Money != 0 {
+		parts = append(parts, msgtemplate.Money(int(float64(d.Money)/hours))+"/h")
+	}
+	parts = append(parts, fmt.Sprintf("%.1f ores/h", float64(d.oreTotal())/hours))
+	if d.Level != 0 {
+		parts = append(parts, fmt.Sprintf("%.1f levels/h", float64(d.Level)/hours))
+	}
+	return strings.Join(parts, ", ")
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
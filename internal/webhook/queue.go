@@ -0,0 +1,445 @@
+package webhook
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"forger-companion/internal/metrics"
+	"io"
+	"log"
+	"math"
+	mathrand "math/rand"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	maxAttempts = 8
+	baseBackoff = 2 * time.Second
+	maxBackoff  = 10 * time.Minute
+
+	// Attachments can be several megabytes, so only so many updates are
+	// kept. The oldest give way to new ones.
+	maxQueued = 50
+	maxDead   = 20
+)
+
+var (
+	deliveriesTotal = metrics.NewCounter("forger_webhook_deliveries_total", "Updates delivered, by notifier.", "notifier")
+	failuresTotal   = metrics.NewCounter("forger_webhook_failures_total", "Failed delivery attempts, by notifier.", "notifier")
+)
+
+// job is one message waiting to be delivered to one notifier. Jobs are
+// stored as JSON files so queued updates survive a restart.
+type job struct {
+	ID          string      `json:"id"`
+	Notifier    string      `json:"notifier"`
+	Message     *Message    `json:"message"`
+	Attachment  *Attachment `json:"attachment,omitempty"`
+	Attempts    int         `json:"attempts"`
+	NextAttempt time.Time   `json:"next_attempt"`
+	LastError   string      `json:"last_error,omitempty"`
+	Created     time.Time   `json:"created"`
+}
+
+// DeliveryStatus summarizes the queue for display.
+type DeliveryStatus struct {
+	Pending      int       `json:"pending"`
+	Delivered    int       `json:"delivered"`
+	Retries      int       `json:"retries"`
+	DeadLettered int       `json:"dead_lettered"`
+	LastError    string    `json:"last_error,omitempty"`
+	LastDelivery time.Time `json:"last_delivery,omitempty"`
+}
+
+// Queue delivers messages in the background, retrying failures with
+// exponential backoff and jitter and honoring rate limits. Messages that
+// keep failing are moved to a dead-letter directory.
+type Queue struct {
+	dir     string
+	resolve func(name string) (Notifier, bool)
+
+	mu        sync.Mutex
+	jobs      map[string]*job
+	blocked   map[string]time.Time // notifier -> rate limited until
+	status    DeliveryStatus
+	sending   string // ID of the job being sent
+	wake      chan struct{}
+	startOnce sync.Once
+
+	ctx    context.Context // cancelled by Close
+	cancel context.CancelFunc
+	done   chan struct{} // closed when run returns
+}
+
+// NewQueue stores pending jobs in dir. resolve looks up the current
+// notifier for a job, so config reloads take effect for queued messages.
+func NewQueue(dir string, resolve func(name string) (Notifier, bool)) *Queue {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Queue{
+		dir:     dir,
+		resolve: resolve,
+		jobs:    make(map[string]*job),
+		blocked: make(map[string]time.Time),
+		wake:    make(chan struct{}, 1),
+		ctx:     ctx,
+		cancel:  cancel,
+		done:    make(chan struct{}),
+	}
+}
+
+func (q *Queue) deadDir() string {
+	return filepath.Join(q.dir, "dead")
+}
+
+// Start loads jobs left over from a previous run and starts delivering.
+func (q *Queue) Start() {
+	q.startOnce.Do(func() {
+		q.load()
+		go q.run()
+	})
+}
+
+// Close stops delivering, cancelling a send in progress, and waits for
+// the worker to finish. Undelivered jobs stay on disk for the next Start.
+func (q *Queue) Close() {
+	q.cancel()
+	q.startOnce.Do(func() { close(q.done) }) // never started
+	<-q.done
+}
+
+// Enqueue schedules msg for delivery to the named notifier.
+func (q *Queue) Enqueue(notifier string, msg *Message) error {
+	id := make([]byte, 8)
+	rand.Read(id)
+
+	j := &job{
+		ID:          time.Now().Format("20060102-150405") + "-" + hex.EncodeToString(id),
+		Notifier:    notifier,
+		Message:     msg,
+		Attachment:  msg.Attachment,
+		NextAttempt: time.Now(),
+		Created:     time.Now(),
+	}
+	if err := q.persist(j); err != nil {
+		return err
+	}
+
+	q.mu.Lock()
+	q.jobs[j.ID] = j
+	q.status.Pending = len(q.jobs)
+	var dropped []*job
+	if len(q.jobs) > maxQueued {
+		dropped = q.oldest(len(q.jobs) - maxQueued)
+	}
+	q.mu.Unlock()
+
+	for _, old := range dropped {
+		q.deadLetter(old, "dropped to make room in a full queue")
+	}
+	q.notify()
+	return nil
+}
+
+// oldest returns up to n of the jobs created first, leaving out the one
+// being sent. The caller holds q.mu.
+func (q *Queue) oldest(n int) []*job {
+	jobs := make([]*job, 0, len(q.jobs))
+	for _, j := range q.jobs {
+		if j.ID != q.sending {
+			jobs = append(jobs, j)
+		}
+	}
+	sort.Slice(jobs, func(a, b int) bool { return jobs[a].Created.Before(jobs[b].Created) })
+	return jobs[:min(n, len(jobs))]
+}
+
+func (q *Queue) Status() DeliveryStatus {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.status
+}
+
+func (q *Queue) notify() {
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (q *Queue) run() {
+	defer close(q.done)
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-q.wake:
+		case <-q.ctx.Done():
+			return
+		}
+		for _, j := range q.due() {
+			if q.ctx.Err() != nil {
+				return
+			}
+			q.attempt(j)
+		}
+	}
+}
+
+// due returns jobs whose retry time has passed and whose notifier isn't
+// rate limited, oldest first.
+func (q *Queue) due() []*job {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	now := time.Now()
+	var ready []*job
+	for _, j := range q.jobs {
+		if j.NextAttempt.After(now) || q.blocked[j.Notifier].After(now) {
+			continue
+		}
+		ready = append(ready, j)
+	}
+	sort.Slice(ready, func(a, b int) bool { return ready[a].Created.Before(ready[b].Created) })
+	return ready
+}
+
+func (q *Queue) attempt(j *job) {
+	q.mu.Lock()
+	if q.jobs[j.ID] != j {
+		q.mu.Unlock()
+		return // dropped since the batch was picked
+	}
+	if q.blocked[j.Notifier].After(time.Now()) {
+		q.mu.Unlock()
+		return // an earlier job in this batch hit the rate limit
+	}
+	q.sending = j.ID
+	q.mu.Unlock()
+	defer func() {
+		q.mu.Lock()
+		q.sending = ""
+		q.mu.Unlock()
+	}()
+
+	n, ok := q.resolve(j.Notifier)
+	if !ok {
+		q.deadLetter(j, fmt.Sprintf("notifier %q is no longer configured", j.Notifier))
+		return
+	}
+
+	msg := *j.Message
+	msg.Attachment = j.Attachment
+	ctx, cancel := context.WithTimeout(q.ctx, time.Minute)
+	err := redactURL(n.Send(ctx, &msg))
+	cancel()
+	if q.ctx.Err() != nil {
+		return // closing; the job is retried after the next Start
+	}
+
+	var limited *rateLimited
+	if errors.As(err, &limited) {
+		q.mu.Lock()
+		q.blocked[j.Notifier] = time.Now().Add(limited.wait)
+		q.mu.Unlock()
+		err = nil
+	}
+
+	if err == nil {
+		q.remove(j)
+		q.mu.Lock()
+		q.status.Delivered++
+		q.status.LastDelivery = time.Now()
+		q.mu.Unlock()
+		deliveriesTotal.Inc(j.Notifier)
+		log.Printf("[Webhook] Update sent via %s", j.Notifier)
+		return
+	}
+
+	j.Attempts++
+	j.LastError = err.Error()
+	failuresTotal.Inc(j.Notifier)
+
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) && statusErr.RetryAfter > 0 {
+		q.mu.Lock()
+		q.blocked[j.Notifier] = time.Now().Add(statusErr.RetryAfter)
+		q.mu.Unlock()
+	}
+
+	if !retryable(err) || j.Attempts >= maxAttempts {
+		q.deadLetter(j, err.Error())
+		return
+	}
+
+	wait := backoff(j.Attempts)
+	if statusErr != nil && statusErr.RetryAfter > wait {
+		wait = statusErr.RetryAfter
+	}
+	j.NextAttempt = time.Now().Add(wait)
+	if perr := q.persist(j); perr != nil {
+		log.Printf("[Webhook] Can't save queued update: %v", perr)
+	}
+
+	q.mu.Lock()
+	q.status.Retries++
+	q.status.LastError = fmt.Sprintf("%s: %v", j.Notifier, err)
+	q.mu.Unlock()
+	log.Printf("[Webhook] %s failed (attempt %d/%d), retrying in %v: %v", j.Notifier, j.Attempts, maxAttempts, wait.Round(time.Second), err)
+}
+
+// backoff doubles the wait with each attempt and adds up to ±50% jitter so
+// several clients don't retry in lockstep.
+func backoff(attempts int) time.Duration {
+	wait := time.Duration(float64(baseBackoff) * math.Pow(2, float64(attempts-1)))
+	if wait > maxBackoff {
+		wait = maxBackoff
+	}
+	jitter := (mathrand.Float64() - 0.5) * float64(wait)
+	return wait + time.Duration(jitter)
+}
+
+// retryable reports whether a failed delivery might succeed later: network
+// errors, timeouts, rate limits and server errors. A bad URL or a rejected
+// certificate won't fix itself.
+func retryable(err error) bool {
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		code := statusErr.StatusCode
+		return code == 408 || code == 429 || code >= 500
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	var opErr *net.OpError // refused, reset, unreachable, lookup failed
+	if errors.As(err, &opErr) {
+		return true
+	}
+	return errors.Is(err, context.DeadlineExceeded) || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// redactURL hides the path and query of the URL in a request error.
+// Webhook URLs and bot API paths carry the credentials, and the error text
+// ends up in logs, the outbox and the stats API.
+func redactURL(err error) error {
+	var urlErr *url.Error
+	if !errors.As(err, &urlErr) {
+		return err
+	}
+	hidden := "(hidden)"
+	if u, perr := url.Parse(urlErr.URL); perr == nil && u.Host != "" {
+		hidden = u.Scheme + "://" + u.Host + "/…"
+	}
+	return &url.Error{Op: urlErr.Op, URL: hidden, Err: urlErr.Err}
+}
+
+func (q *Queue) deadLetter(j *job, reason string) {
+	j.LastError = reason
+	log.Printf("[Webhook] Giving up on update for %s after %d attempts: %s", j.Notifier, j.Attempts, reason)
+
+	if err := os.MkdirAll(q.deadDir(), 0755); err == nil {
+		if data, err := json.MarshalIndent(j, "", "  "); err == nil {
+			os.WriteFile(filepath.Join(q.deadDir(), j.ID+".json"), data, 0644)
+		}
+	}
+	q.remove(j)
+	kept := q.pruneDead()
+
+	q.mu.Lock()
+	q.status.DeadLettered = kept
+	q.status.LastError = fmt.Sprintf("%s: %s", j.Notifier, reason)
+	q.mu.Unlock()
+}
+
+// pruneDead deletes the oldest dead letters beyond maxDead and returns how
+// many are left. Job IDs start with their creation time, so names sort
+// oldest first.
+func (q *Queue) pruneDead() int {
+	entries, err := os.ReadDir(q.deadDir())
+	if err != nil {
+		return 0
+	}
+	var names []string
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.HasSuffix(entry.Name(), ".json") {
+			names = append(names, entry.Name())
+		}
+	}
+	sort.Strings(names)
+	for len(names) > maxDead {
+		os.Remove(filepath.Join(q.deadDir(), names[0]))
+		names = names[1:]
+	}
+	return len(names)
+}
+
+func (q *Queue) remove(j *job) {
+	os.Remove(q.jobPath(j.ID))
+
+	q.mu.Lock()
+	delete(q.jobs, j.ID)
+	q.status.Pending = len(q.jobs)
+	q.mu.Unlock()
+}
+
+func (q *Queue) jobPath(id string) string {
+	return filepath.Join(q.dir, id+".json")
+}
+
+func (q *Queue) persist(j *job) error {
+	if err := os.MkdirAll(q.dir, 0755); err != nil {
+		return err
+	}
+	data, err := json.Marshal(j)
+	if err != nil {
+		return err
+	}
+	tmp := q.jobPath(j.ID) + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, q.jobPath(j.ID))
+}
+
+func (q *Queue) load() {
+	entries, err := os.ReadDir(q.dir)
+	if err != nil {
+		return
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(q.dir, entry.Name()))
+		if err != nil {
+			continue
+		}
+		var j job
+		if err := json.Unmarshal(data, &j); err != nil || j.Message == nil {
+			log.Printf("[Webhook] Skipping unreadable queued update %s", entry.Name())
+			continue
+		}
+		q.jobs[j.ID] = &j
+	}
+	if len(q.jobs) > 0 {
+		log.Printf("[Webhook] Resuming %d queued updates", len(q.jobs))
+	}
+	q.status.Pending = len(q.jobs)
+
+	q.status.DeadLettered = q.pruneDead()
+}
@@ -0,0 +1,69 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"forger-companion/internal/config"
+	"net/http"
+)
+
+// slack posts to a Slack incoming webhook. Incoming webhooks can't upload
+// files, so attachments are left out.
+type slack struct {
+	name string
+	url  config.Secret
+}
+
+func newSlack(cfg config.NotifierConfig) (Notifier, error) {
+	return &slack{name: cfg.Name, url: cfg.URL}, nil
+}
+
+func (s *slack) Name() string { return s.name }
+
+func (s *slack) Send(ctx context.Context, msg *Message) error {
+	url, err := s.url.Value()
+	if err != nil {
+		return err
+	}
+
+	blocks := []map[string]interface{}{
+		{"type": "header", "text": map[string]string{"type": "plain_text", "text": msg.Title}},
+	}
+	if msg.Description != "" {
+		blocks = append(blocks, map[string]interface{}{
+			"type": "section", "text": map[string]string{"type": "mrkdwn", "text": msg.Description},
+		})
+	}
+	if len(msg.Fields) > 0 {
+		// Slack allows at most 10 fields per section
+		var fields []map[string]string
+		for _, f := range msg.Fields {
+			fields = append(fields, map[string]string{"type": "mrkdwn", "text": fmt.Sprintf("*%s*\n%s", f.Name, f.Value)})
+			if len(fields) == 10 {
+				blocks = append(blocks, map[string]interface{}{"type": "section", "fields": fields})
+				fields = nil
+			}
+		}
+		if len(fields) > 0 {
+			blocks = append(blocks, map[string]interface{}{"type": "section", "fields": fields})
+		}
+	}
+	if msg.Footer != "" {
+		blocks = append(blocks, map[string]interface{}{
+			"type": "context", "elements": []map[string]string{{"type": "mrkdwn", "text": msg.Footer}},
+		})
+	}
+
+	payload, _ := json.Marshal(map[string]interface{}{
+		"text":   msg.Title, // notification fallback
+		"blocks": blocks,
+	})
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	return do(s.name, req)
+}
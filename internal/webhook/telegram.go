@@ -0,0 +1,143 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"forger-companion/internal/config"
+	"html"
+	"mime/multipart"
+	"net/http"
+	"strings"
+)
+
+const telegramAPI = "https://api.telegram.org"
+
+// Telegram's limits on visible text, not counting markup.
+const (
+	telegramMessageLimit = 4096
+	telegramCaptionLimit = 1024
+)
+
+// telegram sends messages through the Telegram Bot API, as a photo or
+// animation with a caption when there is an attachment.
+type telegram struct {
+	name    string
+	token   config.Secret
+	chatID  string
+	apiBase string
+}
+
+func newTelegram(cfg config.NotifierConfig) (Notifier, error) {
+	apiBase := cfg.APIBase
+	if apiBase == "" {
+		apiBase = telegramAPI
+	}
+	return &telegram{
+		name:    cfg.Name,
+		token:   cfg.Token,
+		chatID:  cfg.ChatID,
+		apiBase: strings.TrimRight(apiBase, "/"),
+	}, nil
+}
+
+func (t *telegram) Name() string { return t.name }
+
+func (t *telegram) Send(ctx context.Context, msg *Message) error {
+	token, err := t.token.Value()
+	if err != nil {
+		return err
+	}
+
+	if msg.Attachment == nil {
+		payload, _ := json.Marshal(map[string]string{
+			"chat_id":    t.chatID,
+			"text":       t.format(msg, telegramMessageLimit),
+			"parse_mode": "HTML",
+		})
+		req, err := http.NewRequestWithContext(ctx, "POST", t.method(token, "sendMessage"), bytes.NewReader(payload))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		return do(t.name, req)
+	}
+
+	method, field := "sendPhoto", "photo"
+	if msg.Attachment.ContentType == "image/gif" {
+		method, field = "sendAnimation", "animation"
+	}
+
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	writer.WriteField("chat_id", t.chatID)
+	writer.WriteField("caption", t.format(msg, telegramCaptionLimit))
+	writer.WriteField("parse_mode", "HTML")
+	part, err := writer.CreateFormFile(field, msg.Attachment.Filename)
+	if err != nil {
+		return err
+	}
+	if _, err := part.Write(msg.Attachment.Data); err != nil {
+		return err
+	}
+	writer.Close()
+
+	req, err := http.NewRequestWithContext(ctx, "POST", t.method(token, method), body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	return do(t.name, req)
+}
+
+func (t *telegram) method(token, method string) string {
+	return fmt.Sprintf("%s/bot%s/%s", t.apiBase, token, method)
+}
+
+// format renders msg as Telegram HTML showing at most limit characters.
+// The plain text is cut before it's escaped and tagged, so a long message
+// never ends inside a tag or entity.
+func (t *telegram) format(msg *Message, limit int) string {
+	text := &telegramText{left: limit}
+	text.write(msg.Title, true)
+	if msg.Description != "" {
+		text.write("\n"+msg.Description, false)
+	}
+	for _, f := range msg.Fields {
+		text.write("\n", false)
+		text.write(f.Name+":", true)
+		text.write(" "+strings.TrimRight(f.Value, "\n"), false)
+	}
+	return text.b.String()
+}
+
+// telegramText builds HTML from plain text pieces until the visible
+// length reaches a limit, ending with an ellipsis if anything was left
+// out.
+type telegramText struct {
+	b    strings.Builder
+	left int // visible characters still allowed
+	cut  bool
+}
+
+func (t *telegramText) write(s string, bold bool) {
+	if t.cut || s == "" {
+		return
+	}
+	r := []rune(s)
+	if len(r) > t.left {
+		t.cut = true
+		if t.left == 0 {
+			return
+		}
+		r = append(r[:t.left-1], '…')
+	}
+	t.left -= len(r)
+
+	s = html.EscapeString(string(r))
+	if bold {
+		s = "<b>" + s + "</b>"
+	}
+	t.b.WriteString(s)
+}
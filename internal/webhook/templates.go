@@ -0,0 +1,94 @@
+package webhook
+
+import (
+	"forger-companion/internal/data"
+	"forger-companion/internal/msgtemplate"
+	"forger-companion/internal/ocr"
+	"log"
+	"time"
+)
+
+// render applies the notifier's template for msg.Event, if any, to a copy
+// of msg. A template that fails to render falls back to the default
+// message so the update isn't lost.
+func (m *Manager) render(notifier string, msg *Message, d *msgtemplate.Data) *Message {
+	if d == nil {
+		return msg
+	}
+	t, ok := m.settings().Webhook.Template(notifier, msg.Event)
+	if !ok || t.IsZero() {
+		return msg
+	}
+
+	dd := *d
+	dd.Notifier = notifier
+	r, err := t.Render(&dd)
+	if err != nil {
+		log.Printf("[Webhook] %s template for %s: %v", msg.Event, notifier, err)
+		return msg
+	}
+
+	out := *msg
+	if r.Title != "" {
+		out.Title = r.Title
+	}
+	if r.Description != "" {
+		out.Description = r.Description
+	}
+	if r.Color != 0 {
+		out.Color = r.Color
+	}
+	if r.Footer != "" {
+		out.Footer = r.Footer
+	}
+	if len(t.Fields) > 0 {
+		out.Fields = make([]Field, len(r.Fields))
+		for i, f := range r.Fields {
+			out.Fields[i] = Field{Name: f.Name, Value: f.Value, Inline: f.Inline}
+		}
+	}
+	return &out
+}
+
+func (m *Manager) templateData(event string, cycle int) *msgtemplate.Data {
+	d := &msgtemplate.Data{
+		Event: event,
+		Time:  time.Now(),
+		Cycle: cycle,
+		Ores:  data.Ores,
+	}
+	if start, ok := m.progress.started(); ok {
+		d.Session = msgtemplate.Session{
+			Start:    start,
+			Duration: time.Since(start),
+			Cycles:   cycle,
+		}
+	}
+	return d
+}
+
+func statsData(s *ocr.Stats) *msgtemplate.Stats {
+	if s == nil {
+		return nil
+	}
+	return &msgtemplate.Stats{
+		Ores:      s.LegendaryOres,
+		Level:     s.Level,
+		Money:     s.Money,
+		SellValue: s.SellValue(),
+	}
+}
+
+func (d *delta) templateData() *msgtemplate.Delta {
+	if d == nil {
+		return nil
+	}
+	return &msgtemplate.Delta{
+		Ores:    d.Ores,
+		Level:   d.Level,
+		Money:   d.Money,
+		Over:    d.Over,
+		Summary: d.String(),
+		Rates:   d.Rates(),
+	}
+}
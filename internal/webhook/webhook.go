@@ -1,232 +1,246 @@
 package webhook
 
 import (
-	"bytes"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"forger-companion/internal/config"
+	"forger-companion/internal/data"
+	"forger-companion/internal/events"
+	"forger-companion/internal/msgtemplate"
 	"forger-companion/internal/ocr"
-	"image"
-	"image/png"
-	"io"
 	"log"
-	"mime/multipart"
-	"net/http"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
 	"time"
-
-	"github.com/kbinani/screenshot"
 )
 
 type Manager struct {
-	cfg *config.Config
+	cfg        atomic.Pointer[config.Config] // replaced, never changed, on reload
+	mu         sync.RWMutex
+	notifiers  []Notifier
+	queue      *Queue
+	lastCounts map[string]int
+	lastAlert  map[events.Kind]time.Time
+	progress   *progress
 }
 
 func NewManager(cfg *config.Config) *Manager {
-	return &Manager{cfg: cfg}
+	m := &Manager{
+		progress: loadProgress(filepath.Join(config.Dir(), "progress.json")),
+	}
+	m.cfg.Store(cfg)
+	m.buildNotifiers()
+	m.queue = NewQueue(filepath.Join(config.Dir(), "outbox"), m.notifier)
+	m.queue.Start()
+	return m
 }
 
-func (m *Manager) ShouldSendUpdate(cycle int) bool {
-	if !m.cfg.Webhook.Enabled {
-		return false
+// ConfigChanged is called by the config watcher after settings reload.
+func (m *Manager) ConfigChanged(cfg *config.Config) {
+	m.cfg.Store(cfg)
+	m.buildNotifiers()
+}
+
+func (m *Manager) settings() *config.Config {
+	return m.cfg.Load()
+}
+
+// Close stops delivering updates. Those still queued are sent after the
+// next start.
+func (m *Manager) Close() {
+	m.queue.Close()
+}
+
+// Status reports pending, delivered and dead-lettered updates.
+func (m *Manager) Status() DeliveryStatus {
+	return m.queue.Status()
+}
+
+func (m *Manager) notifier(name string) (Notifier, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, n := range m.notifiers {
+		if n.Name() == name {
+			return n, true
+		}
 	}
-	if m.cfg.Webhook.Mode == "webhook" && m.cfg.Webhook.WebhookURL == "" {
-		return false
+	return nil, false
+}
+
+func (m *Manager) buildNotifiers() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.notifiers = nil
+	for _, nc := range m.settings().Webhook.Notifiers {
+		if !nc.Enabled {
+			continue
+		}
+		n, err := NewNotifier(nc)
+		if err != nil {
+			log.Printf("[Webhook] %v", err)
+			continue
+		}
+		m.notifiers = append(m.notifiers, n)
 	}
-	if m.cfg.Webhook.Mode == "bot" && m.cfg.Webhook.DiscordID == "" {
+}
+
+func (m *Manager) ShouldSendUpdate(cycle int) bool {
+	m.mu.RLock()
+	count := len(m.notifiers)
+	m.mu.RUnlock()
+
+	webhook := m.settings().Webhook
+	if !webhook.Enabled || count == 0 {
 		return false
 	}
-	return cycle > 0 && cycle%m.cfg.Webhook.CycleInterval == 0
+	return cycle > 0 && cycle%webhook.CycleInterval == 0
+}
+
+// StartSession is called when the macro starts. Progress deltas continue
+// from the previous session if it ended recently, e.g. after a restart.
+func (m *Manager) StartSession() {
+	m.progress.begin()
 }
 
 func (m *Manager) TrackStats() bool {
-	return m.cfg.Webhook.TrackStats
+	return m.settings().Webhook.TrackStats
 }
 
 func (m *Manager) SendUpdate(cycle int, stats *ocr.Stats) error {
-	if m.cfg.Webhook.Mode == "webhook" {
-		return m.sendWebhook(cycle, stats)
+	if min := m.settings().Webhook.NotifyMinRarity; min != 0 && !m.hasNewFinds(stats, min) {
+		log.Printf("[Webhook] No new %s+ finds, skipping update", min)
+		return nil
 	}
-	return m.sendBotDM(cycle, stats)
-}
 
-func (m *Manager) sendWebhook(cycle int, stats *ocr.Stats) error {
-	// Capture screenshot
-	img, err := m.captureScreen()
+	// Capture screenshot or GIF
+	attachment, err := m.captureAttachment(cycle)
 	if err != nil {
 		return err
 	}
 
-	// Create embed
-	embed := map[string]interface{}{
-		"title": "🔨 Macro Progress Update",
-		"color": 5793522,
-		"fields": []map[string]interface{}{
-			{"name": "Cycle", "value": fmt.Sprintf("#%d", cycle), "inline": true},
-			{"name": "Time", "value": fmt.Sprintf("<t:%d:R>", time.Now().Unix()), "inline": true},
-		},
-		"image": map[string]string{
-			"url": fmt.Sprintf("attachment://progress_cycle_%d.png", cycle),
-		},
-		"footer": map[string]string{
-			"text": "Forger Companion",
+	msg := &Message{
+		Event:     "progress",
+		Title:     "🔨 Macro Progress Update",
+		Color:     5793522,
+		Footer:    "Forger Companion",
+		Timestamp: time.Now(),
+		Cycle:     cycle,
+		Fields: []Field{
+			{Name: "Cycle", Value: fmt.Sprintf("#%d", cycle), Inline: true},
 		},
+		Attachment: attachment,
 	}
 
+	var sinceLast, sinceStart *delta
+	if stats != nil {
+		sinceLast, sinceStart = m.progress.record(stats)
+	}
+	tmplData := m.templateData("progress", cycle)
+	tmplData.Stats = statsData(stats)
+	tmplData.SinceLast = m.filterDelta(sinceLast).templateData()
+	tmplData.SinceStart = m.filterDelta(sinceStart).templateData()
+
 	// Add stats if provided
 	if stats != nil {
-		fields := embed["fields"].([]map[string]interface{})
-		
-		if len(stats.LegendaryOres) > 0 {
-			oresText := ""
-			for name, count := range stats.LegendaryOres {
-				oresText += fmt.Sprintf("• %s: %d\n", name, count)
-			}
-			fields = append(fields, map[string]interface{}{
-				"name":   "🌟 Legendary/Mythic Ores",
-				"value":  oresText,
-				"inline": false,
+		if sinceLast != nil {
+			msg.Fields = append(msg.Fields, Field{Name: "📈 Since Last Update", Value: m.filterDelta(sinceLast).String()})
+		}
+		if sinceStart != nil {
+			msg.Fields = append(msg.Fields, Field{
+				Name:  fmt.Sprintf("🕒 This Session (%v)", sinceStart.Over.Round(time.Minute)),
+				Value: m.filterDelta(sinceStart).String(),
 			})
+			if rates := sinceStart.Rates(); rates != "" {
+				msg.Fields = append(msg.Fields, Field{Name: "⚡ Per Hour", Value: rates})
+			}
+		}
+		if value := stats.SellValue(); value > 0 && len(m.filterOres(stats.LegendaryOres)) > 0 {
+			msg.Fields = append(msg.Fields, Field{Name: "💎 Sell Value", Value: msgtemplate.Money(value), Inline: true})
 		}
-		
 		if stats.Level > 0 {
-			fields = append(fields, map[string]interface{}{
-				"name":   "📊 Level",
-				"value":  fmt.Sprintf("%d", stats.Level),
-				"inline": true,
-			})
+			msg.Fields = append(msg.Fields, Field{Name: "📊 Level", Value: fmt.Sprintf("%d", stats.Level), Inline: true})
 		}
-		
 		if stats.Money > 0 {
-			fields = append(fields, map[string]interface{}{
-				"name":   "💰 Money",
-				"value":  fmt.Sprintf("$%d", stats.Money),
-				"inline": true,
-			})
+			msg.Fields = append(msg.Fields, Field{Name: "💰 Money", Value: msgtemplate.Money(stats.Money), Inline: true})
 		}
-		
-		embed["fields"] = fields
-	}
-
-	// Create multipart form
-	body := &bytes.Buffer{}
-	writer := multipart.NewWriter(body)
-
-	// Add image
-	imgBuf := &bytes.Buffer{}
-	if err := png.Encode(imgBuf, img); err != nil {
-		return err
-	}
-	
-	part, err := writer.CreateFormFile("file", fmt.Sprintf("progress_cycle_%d.png", cycle))
-	if err != nil {
-		return err
-	}
-	if _, err := io.Copy(part, imgBuf); err != nil {
-		return err
-	}
-
-	// Add payload
-	payload := map[string]interface{}{
-		"embeds": []interface{}{embed},
 	}
-	payloadJSON, _ := json.Marshal(payload)
-	writer.WriteField("payload_json", string(payloadJSON))
-	writer.Close()
 
-	// Send request
-	req, err := http.NewRequest("POST", m.cfg.Webhook.WebhookURL, body)
-	if err != nil {
-		return err
-	}
-	req.Header.Set("Content-Type", writer.FormDataContentType())
+	return m.broadcastTo(nil, msg, tmplData)
+}
 
-	client := &http.Client{Timeout: 30 * time.Second}
-	resp, err := client.Do(req)
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
+// Broadcast queues msg for every enabled notifier. Delivery happens in the
+// background; failures are retried and show up in Status.
+func (m *Manager) Broadcast(msg *Message) error {
+	return m.broadcastTo(nil, msg, nil)
+}
 
-	if resp.StatusCode != 200 && resp.StatusCode != 204 {
-		return fmt.Errorf("webhook failed: %d", resp.StatusCode)
+// broadcastTo queues msg for the named notifiers, or all when names is
+// empty. With template data, each notifier's template for the event is
+// applied first.
+func (m *Manager) broadcastTo(names []string, msg *Message, d *msgtemplate.Data) error {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var errs []error
+	for _, n := range m.notifiers {
+		if len(names) > 0 && !contains(names, n.Name()) {
+			continue
+		}
+		if err := m.queue.Enqueue(n.Name(), m.render(n.Name(), msg, d)); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", n.Name(), err))
+		}
 	}
-
-	log.Println("[Webhook] Update sent successfully!")
-	return nil
+	return errors.Join(errs...)
 }
 
-func (m *Manager) sendBotDM(cycle int, stats *ocr.Stats) error {
-	// Capture screenshot
-	img, err := m.captureScreen()
-	if err != nil {
-		return err
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
 	}
+	return false
+}
 
-	// Create multipart form
-	body := &bytes.Buffer{}
-	writer := multipart.NewWriter(body)
-
-	// Add image
-	imgBuf := &bytes.Buffer{}
-	if err := png.Encode(imgBuf, img); err != nil {
-		return err
-	}
-	
-	part, err := writer.CreateFormFile("image", fmt.Sprintf("progress_cycle_%d.png", cycle))
-	if err != nil {
-		return err
-	}
-	if _, err := io.Copy(part, imgBuf); err != nil {
-		return err
+// hasNewFinds reports whether any ore of at least min rarity increased
+// since the last call.
+func (m *Manager) hasNewFinds(stats *ocr.Stats, min data.Rarity) bool {
+	if stats == nil {
+		return false
 	}
 
-	// Add form fields
-	writer.WriteField("discord_id", m.cfg.Webhook.DiscordID)
-	writer.WriteField("cycle", fmt.Sprintf("%d", cycle))
-	writer.WriteField("timestamp", time.Now().Format(time.RFC3339))
-	
-	// TODO: Add license_key from auth
-	
-	writer.Close()
-
-	// Send to bot API
-	botURL := "https://forger-production.up.railway.app/api/progress"
-	req, err := http.NewRequest("POST", botURL, body)
-	if err != nil {
-		return err
+	found := false
+	for name, count := range stats.LegendaryOres {
+		if data.Ores[name].Rarity.AtLeast(min) && count > m.lastCounts[name] {
+			found = true
+		}
 	}
-	req.Header.Set("Content-Type", writer.FormDataContentType())
+	m.lastCounts = stats.LegendaryOres
+	return found
+}
 
-	client := &http.Client{Timeout: 30 * time.Second}
-	resp, err := client.Do(req)
-	if err != nil {
-		return err
+// filterDelta drops ores below the configured minimum rarity.
+func (m *Manager) filterDelta(d *delta) *delta {
+	if d == nil {
+		return nil
 	}
-	defer resp.Body.Close()
+	filtered := *d
+	filtered.Ores = m.filterOres(d.Ores)
+	return &filtered
+}
 
-	if resp.StatusCode != 200 {
-		return fmt.Errorf("bot API failed: %d", resp.StatusCode)
+func (m *Manager) filterOres(ores map[string]int) map[string]int {
+	min := m.settings().Webhook.MinRarity
+	if min == 0 {
+		return ores
 	}
 
-	log.Println("[Webhook] Bot DM sent successfully!")
-	return nil
-}
-
-func (m *Manager) captureScreen() (image.Image, error) {
-	bounds := screenshot.GetDisplayBounds(0)
-	img, err := screenshot.CaptureRect(bounds)
-	if err != nil {
-		return nil, err
-	}
-	
-	// Resize to half for smaller file size
-	resized := image.NewRGBA(image.Rect(0, 0, bounds.Dx()/2, bounds.Dy()/2))
-	// Simple nearest-neighbor resize
-	for y := 0; y < bounds.Dy()/2; y++ {
-		for x := 0; x < bounds.Dx()/2; x++ {
-			resized.Set(x, y, img.At(x*2, y*2))
+	filtered := make(map[string]int)
+	for name, count := range ores {
+		if data.Ores[name].Rarity.AtLeast(min) {
+			filtered[name] = count
 		}
 	}
-	
-	return resized, nil
+	return filtered
 }
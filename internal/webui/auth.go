@@ -0,0 +1,204 @@
+package webui
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"forger-companion/internal/config"
+	"log"
+	"net"
+	"net/http"
+	"strings"
+)
+
+const (
+	scopeRead    = "read"
+	scopeControl = "control"
+
+	sessionCookie = "forger_session"
+	csrfCookie    = "forger_csrf"
+	csrfHeader    = "X-CSRF-Token"
+)
+
+// guard wraps an API handler with authentication and CSRF checks. Requests
+// other than GET need the control scope unless readOnly is set, for
+// endpoints like /api/scan that only look.
+//
+// Scripts authenticate with "Authorization: Bearer <token>". The embedded
+// UI is paired through /pair, which stores the token in a cookie. Browser
+// requests that change something must echo the CSRF cookie in the
+// X-CSRF-Token header, which other sites can't read.
+func (s *Server) guard(h http.HandlerFunc, readOnly bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		need := scopeRead
+		if r.Method != "GET" && r.Method != "HEAD" && !readOnly {
+			need = scopeControl
+		}
+
+		web := s.settings().Web
+		bearer, hasBearer := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		switch {
+		case !web.AuthRequired():
+			// Loopback only and nothing paired: any local client may use
+			// the API, but only under a loopback host name so a page on
+			// another site can't rebind its domain to us.
+			if !loopbackHost(r.Host) {
+				writeError(w, http.StatusForbidden, fmt.Errorf("host %q not allowed", r.Host))
+				return
+			}
+		case hasBearer:
+			// Browsers never add this header by themselves, so no CSRF check
+			if !hasScope(s.tokenScope(bearer), need) {
+				s.deny(w, r, bearer)
+				return
+			}
+		default:
+			cookie, err := r.Cookie(sessionCookie)
+			if err != nil || !hasScope(s.tokenScope(cookie.Value), need) {
+				s.deny(w, r, "")
+				return
+			}
+		}
+
+		if !hasBearer && need == scopeControl && fromBrowser(r) && !validCSRF(r) {
+			writeError(w, http.StatusForbidden, fmt.Errorf("missing or invalid %s header", csrfHeader))
+			return
+		}
+		h(w, r)
+	}
+}
+
+func (s *Server) deny(w http.ResponseWriter, r *http.Request, token string) {
+	if token != "" && s.tokenScope(token) != "" {
+		writeError(w, http.StatusForbidden, fmt.Errorf("token may not %s %s", r.Method, r.URL.Path))
+		return
+	}
+	w.Header().Set("WWW-Authenticate", `Bearer realm="forger-companion"`)
+	writeError(w, http.StatusUnauthorized, fmt.Errorf("not paired: scan the pairing QR code in the app"))
+}
+
+type pairedToken struct {
+	value string
+	scope string
+}
+
+// loadTokens resolves the paired tokens in cfg, which may live in the OS
+// keyring, so requests don't look each one up again. A value shared by
+// several devices can't tell them apart, so it is dropped rather than
+// given whichever scope comes first.
+func (s *Server) loadTokens(cfg *config.Config) {
+	values := make(map[string][]string, len(cfg.Web.Tokens))
+	tokens := make([]pairedToken, 0, len(cfg.Web.Tokens))
+	for _, t := range cfg.Web.Tokens {
+		value, err := t.Token.Value()
+		if err != nil {
+			log.Printf("[WebUI] Token %s: %v", t.Name, err)
+			continue
+		}
+		values[value] = append(values[value], t.Name)
+		tokens = append(tokens, pairedToken{value: value, scope: t.Scope})
+	}
+
+	for _, names := range values {
+		if len(names) > 1 {
+			log.Printf("[WebUI] Devices %s share a token, ignoring it until they are paired again", strings.Join(names, ", "))
+		}
+	}
+	unique := tokens[:0]
+	for _, t := range tokens {
+		if len(values[t.value]) == 1 {
+			unique = append(unique, t)
+		}
+	}
+	s.tokens.Store(&unique)
+}
+
+// tokenScope returns the scope of the paired token matching value, or ""
+// if there is none or more than one.
+func (s *Server) tokenScope(value string) string {
+	if value == "" {
+		return ""
+	}
+	scope, matches := "", 0
+	for _, t := range *s.tokens.Load() {
+		if subtle.ConstantTimeCompare([]byte(t.value), []byte(value)) == 1 {
+			scope = t.scope
+			matches++
+		}
+	}
+	if matches != 1 {
+		return ""
+	}
+	return scope
+}
+
+func hasScope(have, need string) bool {
+	return have == scopeControl || (have == scopeRead && need == scopeRead)
+}
+
+func loopbackHost(hostport string) bool {
+	host := hostport
+	if h, _, err := net.SplitHostPort(hostport); err == nil {
+		host = h
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(strings.Trim(host, "[]"))
+	return ip != nil && ip.IsLoopback()
+}
+
+// fromBrowser reports whether r may have been sent by a browser on some
+// other site's behalf: it carries our session cookie or browser-only
+// headers.
+func fromBrowser(r *http.Request) bool {
+	if _, err := r.Cookie(sessionCookie); err == nil {
+		return true
+	}
+	return r.Header.Get("Origin") != "" || r.Header.Get("Sec-Fetch-Site") != ""
+}
+
+func validCSRF(r *http.Request) bool {
+	cookie, err := r.Cookie(csrfCookie)
+	header := r.Header.Get(csrfHeader)
+	return err == nil && cookie.Value != "" && subtle.ConstantTimeCompare([]byte(cookie.Value), []byte(header)) == 1
+}
+
+// withCSRFCookie hands the UI its CSRF token along with the page.
+func (s *Server) withCSRFCookie(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := r.Cookie(csrfCookie); err != nil {
+			buf := make([]byte, 16)
+			rand.Read(buf)
+			http.SetCookie(w, &http.Cookie{
+				Name:     csrfCookie,
+				Value:    hex.EncodeToString(buf),
+				Path:     "/",
+				Secure:   s.settings().Web.TLS,
+				SameSite: http.SameSiteStrictMode,
+			})
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+// handlePair is where the pairing QR code points. A valid token is kept
+// in a cookie and the browser is sent on to the UI.
+func (s *Server) handlePair(w http.ResponseWriter, r *http.Request) {
+	token := r.URL.Query().Get("token")
+	if s.tokenScope(token) == "" {
+		writeError(w, http.StatusUnauthorized, fmt.Errorf("unknown or revoked pairing token"))
+		return
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookie,
+		Value:    token,
+		Path:     "/",
+		MaxAge:   365 * 24 * 60 * 60,
+		HttpOnly: true,
+		Secure:   s.settings().Web.TLS,
+		SameSite: http.SameSiteLaxMode,
+	})
+	http.Redirect(w, r, "/", http.StatusSeeOther)
+}
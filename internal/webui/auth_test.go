@@ -0,0 +1,90 @@
+package webui
+
+import (
+	"forger-companion/internal/config"
+	"forger-companion/internal/events"
+	"forger-companion/internal/service"
+	"net/url"
+	"path/filepath"
+	"testing"
+)
+
+// idleMacro is a macro that is never started.
+type idleMacro struct{ service.Macro }
+
+func (idleMacro) ConfigChanged(*config.Config) {}
+
+// newTestServer keeps settings and secrets in a temporary directory.
+func newTestServer(t *testing.T) *Server {
+	t.Helper()
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", dir)
+	config.SetPath(filepath.Join(dir, "settings.json"))
+	t.Cleanup(func() { config.SetPath("") })
+
+	svc := service.New(config.Default(), events.NewBus(), nil, idleMacro{})
+	return NewServer(svc)
+}
+
+func pair(t *testing.T, s *Server, name, scope string) string {
+	t.Helper()
+	link, err := s.svc.Config.PairDevice(name, scope)
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, err := url.Parse(link)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return u.Query().Get("token")
+}
+
+func TestRevokedTokenKeyIsNotReused(t *testing.T) {
+	s := newTestServer(t)
+
+	first := pair(t, s, "phone", scopeRead)
+	second := pair(t, s, "tablet", scopeControl)
+	if err := s.svc.Config.RevokeDevice("phone"); err != nil {
+		t.Fatal(err)
+	}
+	third := pair(t, s, "laptop", scopeRead)
+
+	// Settings as the app would find them after a restart.
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.loadTokens(cfg)
+
+	tests := []struct {
+		name, token, want string
+	}{
+		{"revoked", first, ""},
+		{"kept", second, scopeControl},
+		{"paired after revoke", third, scopeRead},
+	}
+	for _, tt := range tests {
+		if got := s.tokenScope(tt.token); got != tt.want {
+			t.Errorf("%s token scope = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestSharedTokenIsRejected(t *testing.T) {
+	s := newTestServer(t)
+
+	cfg := config.Default()
+	cfg.Web.Tokens = []config.WebToken{
+		{Name: "phone", Token: "same", Scope: scopeRead},
+		{Name: "tablet", Token: "same", Scope: scopeControl},
+		{Name: "laptop", Token: "other", Scope: scopeRead},
+	}
+	s.loadTokens(cfg)
+
+	if got := s.tokenScope("same"); got != "" {
+		t.Errorf("shared token scope = %q, want none", got)
+	}
+	if got := s.tokenScope("other"); got != scopeRead {
+		t.Errorf("other token scope = %q, want %q", got, scopeRead)
+	}
+}
@@ -0,0 +1,126 @@
+package webui
+
+import (
+	"encoding/json"
+	"fmt"
+	"forger-companion/internal/events"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// clientBuffer is how many events may queue for a slow client before it is
+// disconnected; it then reconnects and catches up from the bus history.
+const clientBuffer = 64
+
+const heartbeat = 15 * time.Second
+
+// handleEvents streams bus events as Server-Sent Events. The event id is
+// the sequence number, so a reconnecting EventSource resumes through
+// Last-Event-ID; other clients can pass ?since=<seq>. Clients with neither
+// start at the next event, since they load the current state themselves.
+// When events were missed a "resync" event tells the client to reload its
+// state.
+func (s *Server) handleEvents(w http.ResponseWriter, r *http.Request) {
+	if !allowMethods(w, r, "GET") {
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, fmt.Errorf("streaming not supported"))
+		return
+	}
+
+	since, resume, err := lastEventID(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	// Subscribe before reading the history so nothing falls in between
+	live := make(chan events.Event, clientBuffer)
+	overflow := make(chan struct{})
+	unsubscribe := s.bus.Subscribe(func(e events.Event) {
+		select {
+		case live <- e:
+		default:
+			select {
+			case <-overflow:
+			default:
+				close(overflow)
+			}
+		}
+	})
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprint(w, "retry: 3000\n\n")
+
+	var missed []events.Event
+	if resume {
+		var complete bool
+		missed, complete = s.bus.Since(since)
+		if !complete {
+			fmt.Fprint(w, "event: resync\ndata: {}\n\n")
+		}
+	} else {
+		since = s.bus.Seq()
+	}
+	last := since
+	for _, e := range missed {
+		if err := writeEvent(w, e); err != nil {
+			return
+		}
+		last = e.Seq
+	}
+	flusher.Flush()
+
+	ticker := time.NewTicker(heartbeat)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-overflow:
+			return
+		case <-ticker.C:
+			fmt.Fprint(w, ": ping\n\n")
+		case e := <-live:
+			if e.Seq <= last {
+				continue // already sent from the history
+			}
+			if err := writeEvent(w, e); err != nil {
+				return
+			}
+		}
+		flusher.Flush()
+	}
+}
+
+func writeEvent(w http.ResponseWriter, e events.Event) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", e.Seq, e.Kind, data)
+	return err
+}
+
+// lastEventID returns the client's cursor; ok is false when it sent none.
+func lastEventID(r *http.Request) (seq uint64, ok bool, err error) {
+	id := r.Header.Get("Last-Event-ID")
+	if id == "" {
+		id = r.URL.Query().Get("since")
+	}
+	if id == "" {
+		return 0, false, nil
+	}
+	seq, err = strconv.ParseUint(id, 10, 64)
+	if err != nil {
+		return 0, false, fmt.Errorf("invalid event id %q", id)
+	}
+	return seq, true, nil
+}
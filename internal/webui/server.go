@@ -1,66 +1,218 @@
 package webui
 
 import (
+	"crypto/tls"
 	"embed"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"forger-companion/internal/app"
 	"forger-companion/internal/config"
+	"forger-companion/internal/events"
+	"forger-companion/internal/metrics"
+	"forger-companion/internal/service"
+	"io"
+	"io/fs"
 	"log"
 	"net/http"
+	"path/filepath"
+	"sync/atomic"
+	"time"
 )
 
 //go:embed static/*
 var staticFiles embed.FS
 
 type Server struct {
-	app *app.App
-	cfg *config.Config
+	svc    *service.Service
+	bus    *events.Bus
+	tokens atomic.Pointer[[]pairedToken] // resolved when settings are published
 }
 
-func NewServer(application *app.App, cfg *config.Config) *Server {
-	return &Server{
-		app: application,
-		cfg: cfg,
+func NewServer(svc *service.Service) *Server {
+	s := &Server{
+		svc: svc,
+		bus: svc.Bus,
 	}
+	s.loadTokens(svc.Config.Current())
+	svc.Config.Subscribe(s.loadTokens)
+	return s
 }
 
-func (s *Server) Start(port int) error {
+// settings returns the settings in effect for this request.
+func (s *Server) settings() *config.Config {
+	return s.svc.Config.Current()
+}
+
+// Handler returns the routes for the API and the embedded UI.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+
 	// Serve static files
-	http.Handle("/", http.FileServer(http.FS(staticFiles)))
-	
+	static, _ := fs.Sub(staticFiles, "static")
+	mux.Handle("/", s.withCSRFCookie(http.FileServer(http.FS(static))))
+	mux.HandleFunc("/pair", s.handlePair)
+
 	// API endpoints
-	http.HandleFunc("/api/scan", s.handleScan)
-	http.HandleFunc("/api/macro/toggle", s.handleMacroToggle)
-	http.HandleFunc("/api/config", s.handleConfig)
-	
-	addr := fmt.Sprintf("localhost:%d", port)
-	log.Printf("[WebUI] Starting server at http://%s", addr)
-	
-	return http.ListenAndServe(addr, nil)
+	mux.HandleFunc("/api/scan", s.guard(s.handleScan, true))
+	mux.HandleFunc("/api/macro", s.guard(s.handleMacro, false))
+	mux.HandleFunc("/api/macro/toggle", s.guard(s.handleMacroToggle, false))
+	mux.HandleFunc("/api/config", s.guard(s.handleConfig, false))
+	mux.HandleFunc("/api/profiles", s.guard(s.handleProfiles, false))
+	mux.HandleFunc("/api/stats", s.guard(s.handleStats, false))
+	mux.HandleFunc("/api/events", s.guard(s.handleEvents, false))
+	mux.HandleFunc("/metrics", s.guard(metrics.Handler().ServeHTTP, false))
+	return mux
+}
+
+// Start serves on web.bind and web.port until the server fails. Changes
+// to those settings apply after a restart.
+func (s *Server) Start() error {
+	web := s.settings().Web
+	srv := &http.Server{
+		Addr:              web.Addr(),
+		Handler:           s.Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	if web.AuthRequired() && len(web.Tokens) == 0 {
+		log.Printf("[WebUI] Listening on %s but no device is paired yet; pair one from the app", web.Bind)
+	}
+
+	if !web.TLS {
+		log.Printf("[WebUI] Starting server at %s", web.URL())
+		return srv.ListenAndServe()
+	}
+
+	cert, err := loadCertificate(filepath.Join(config.Dir(), "web"))
+	if err != nil {
+		return fmt.Errorf("tls certificate: %w", err)
+	}
+	srv.TLSConfig = &tls.Config{Certificates: []tls.Certificate{cert}, MinVersion: tls.VersionTLS12}
+	log.Printf("[WebUI] Starting server at %s (certificate SHA-256 %s)", web.URL(), fingerprint(cert))
+	return srv.ListenAndServeTLS("", "")
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+func allowMethods(w http.ResponseWriter, r *http.Request, methods ...string) bool {
+	for _, m := range methods {
+		if r.Method == m {
+			return true
+		}
+	}
+	w.Header().Set("Allow", fmt.Sprint(methods))
+	writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", r.Method))
+	return false
 }
 
 func (s *Server) handleScan(w http.ResponseWriter, r *http.Request) {
-	// TODO: Implement scan endpoint
-	json.NewEncoder(w).Encode(map[string]interface{}{
-		"status": "ok",
-	})
+	if !allowMethods(w, r, "GET", "POST") {
+		return
+	}
+	result, err := s.svc.Scan.Scan()
+	switch {
+	case errors.Is(err, service.ErrNoForgeUI):
+		writeError(w, http.StatusConflict, err)
+	case errors.Is(err, service.ErrNoRegion):
+		writeError(w, http.StatusBadRequest, err)
+	case err != nil:
+		writeError(w, http.StatusInternalServerError, err)
+	default:
+		writeJSON(w, http.StatusOK, result)
+	}
+}
+
+type macroRequest struct {
+	Action string `json:"action"` // "start", "stop", "pause", "resume" or "toggle"
+}
+
+func (s *Server) handleMacro(w http.ResponseWriter, r *http.Request) {
+	if !allowMethods(w, r, "GET", "POST") {
+		return
+	}
+	if r.Method == "POST" {
+		var req macroRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		if err := s.svc.Macro.Do(req.Action); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+	}
+	writeJSON(w, http.StatusOK, s.svc.Macro.Status())
 }
 
 func (s *Server) handleMacroToggle(w http.ResponseWriter, r *http.Request) {
-	// TODO: Implement macro toggle
-	json.NewEncoder(w).Encode(map[string]interface{}{
-		"status": "ok",
-	})
+	if !allowMethods(w, r, "POST") {
+		return
+	}
+	if err := s.svc.Macro.Toggle(); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, s.svc.Macro.Status())
 }
 
 func (s *Server) handleConfig(w http.ResponseWriter, r *http.Request) {
-	if r.Method == "GET" {
-		json.NewEncoder(w).Encode(s.cfg)
-	} else if r.Method == "POST" {
-		// TODO: Update config
-		json.NewEncoder(w).Encode(map[string]interface{}{
-			"status": "ok",
-		})
+	if !allowMethods(w, r, "GET", "POST", "PATCH") {
+		return
+	}
+	if r.Method != "GET" {
+		patch, err := io.ReadAll(io.LimitReader(r.Body, 1<<20))
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		if err := s.svc.Config.Update(patch); err != nil {
+			writeError(w, http.StatusUnprocessableEntity, err)
+			return
+		}
+	}
+	writeJSON(w, http.StatusOK, s.settings().Redacted())
+}
+
+type profileRequest struct {
+	Action string `json:"action"` // "switch", "create", "clone" or "delete"
+	Name   string `json:"name"`
+	Source string `json:"source"` // profile to clone from, empty for base settings
+}
+
+func (s *Server) handleProfiles(w http.ResponseWriter, r *http.Request) {
+	if !allowMethods(w, r, "GET", "POST") {
+		return
+	}
+	if r.Method == "POST" {
+		var req profileRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		if err := s.svc.Config.ProfileAction(req.Action, req.Name, req.Source); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+	}
+
+	profiles, err := s.svc.Config.Profiles()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, profiles)
+}
+
+func (s *Server) handleStats(w http.ResponseWriter, r *http.Request) {
+	if !allowMethods(w, r, "GET") {
+		return
 	}
+	writeJSON(w, http.StatusOK, s.svc.Stats.Snapshot())
 }
@@ -0,0 +1,220 @@
+"use strict";
+
+const $ = (id) => document.getElementById(id);
+
+function cookie(name) {
+  const match = document.cookie.match(new RegExp("(?:^|; )" + name + "=([^;]*)"));
+  return match ? decodeURIComponent(match[1]) : "";
+}
+
+// Requests that change something echo the CSRF cookie the page came with.
+async function api(path, options = {}) {
+  const res = await fetch(path, {
+    ...options,
+    headers: {
+      "Content-Type": "application/json",
+      "X-CSRF-Token": cookie("forger_csrf"),
+      ...(options.headers || {}),
+    },
+  });
+  const body = await res.json().catch(() => ({}));
+  if (!res.ok) {
+    throw new Error(body.error || res.statusText);
+  }
+  return body;
+}
+
+function showError(err) {
+  const el = $("error");
+  el.textContent = err ? err.message : "";
+  el.hidden = !err;
+}
+
+function money(n) {
+  if (n >= 1e9) return "$" + (n / 1e9).toFixed(1) + "B";
+  if (n >= 1e6) return "$" + (n / 1e6).toFixed(1) + "M";
+  if (n >= 1e3) return "$" + (n / 1e3).toFixed(1) + "K";
+  return "$" + n;
+}
+
+function duration(seconds) {
+  const h = Math.floor(seconds / 3600);
+  const m = Math.floor((seconds % 3600) / 60);
+  return h ? `${h}h ${m}m` : `${m}m`;
+}
+
+function renderMacro(status) {
+  $("macro-state").textContent = status.state;
+  $("macro-cycle").textContent = status.state === "stopped" ? "" : `(cycle ${status.cycle})`;
+  document.querySelectorAll("[data-action]").forEach((btn) => {
+    const action = btn.dataset.action;
+    btn.disabled =
+      (action === "start" && status.state !== "stopped") ||
+      (action === "pause" && status.state !== "running") ||
+      (action === "resume" && status.state !== "paused") ||
+      (action === "stop" && status.state === "stopped");
+  });
+}
+
+function renderSession(stats) {
+  const report = stats.session || stats.last_report;
+  const rows = [];
+  if (report) {
+    rows.push(["", stats.session ? "Current session" : "Last session"]);
+    rows.push(["Duration", duration(report.duration_seconds)]);
+    rows.push(["Cycles", `${report.cycles} (avg ${report.avg_cycle_seconds.toFixed(1)}s)`]);
+    rows.push(["Sells", `${report.sells_succeeded} ok, ${report.sells_failed} failed`]);
+    if (report.money_end) rows.push(["Money", `${money(report.money_end)} (+${money(report.money_end - report.money_start)})`]);
+    if (report.level_end) rows.push(["Level", `${report.level_end} (+${report.level_end - report.level_start})`]);
+    for (const [name, n] of Object.entries(report.ores_gained || {})) {
+      rows.push([name, "+" + n]);
+    }
+  } else {
+    rows.push(["", "No session yet"]);
+  }
+  $("session").replaceChildren(
+    ...rows.flatMap(([k, v]) => {
+      const dt = document.createElement("dt");
+      const dd = document.createElement("dd");
+      dt.textContent = k;
+      dd.textContent = v;
+      return [dt, dd];
+    })
+  );
+
+  const w = stats.webhook;
+  $("webhook").textContent = `Webhooks: ${w.delivered} sent, ${w.pending} pending, ${w.dead_lettered} failed`;
+}
+
+function renderScan(result) {
+  $("multiplier").textContent = result.total_multiplier.toFixed(2) + "x";
+  $("ores").replaceChildren(
+    ...Object.values(result.ores || {}).map((ore) => {
+      const li = document.createElement("li");
+      li.textContent = `${ore.name} x${ore.count} (${ore.multiplier}x)`;
+      return li;
+    })
+  );
+}
+
+async function loadConfig() {
+  const cfg = await api("/api/config");
+  $("hold-duration").value = cfg.macro_settings.hold_duration;
+  $("auto-sell").checked = cfg.macro_settings.auto_sell;
+  $("cycle-interval").value = cfg.webhook.cycle_interval;
+  $("webhook-enabled").checked = cfg.webhook.enabled;
+}
+
+async function refresh() {
+  try {
+    const stats = await api("/api/stats");
+    renderMacro(stats.macro);
+    renderSession(stats);
+    showError(null);
+  } catch (err) {
+    showError(err);
+  }
+}
+
+document.querySelectorAll("[data-action]").forEach((btn) => {
+  btn.addEventListener("click", async () => {
+    try {
+      renderMacro(await api("/api/macro", {
+        method: "POST",
+        body: JSON.stringify({ action: btn.dataset.action }),
+      }));
+      showError(null);
+    } catch (err) {
+      showError(err);
+    }
+  });
+});
+
+$("scan").addEventListener("click", async () => {
+  try {
+    renderScan(await api("/api/scan", { method: "POST" }));
+    showError(null);
+  } catch (err) {
+    $("multiplier").textContent = "–";
+    showError(err);
+  }
+});
+
+$("save").addEventListener("click", async () => {
+  try {
+    await api("/api/config", {
+      method: "PATCH",
+      body: JSON.stringify({
+        macro_settings: {
+          hold_duration: Number($("hold-duration").value),
+          auto_sell: $("auto-sell").checked,
+        },
+        webhook: {
+          enabled: $("webhook-enabled").checked,
+          cycle_interval: Number($("cycle-interval").value),
+        },
+      }),
+    });
+    showError(null);
+  } catch (err) {
+    showError(err);
+  }
+});
+
+api("/api/profiles")
+  .then((p) => ($("profile").textContent = p.active || "base settings"))
+  .catch(() => {});
+loadConfig().catch(showError);
+refresh();
+
+// Live updates. EventSource reconnects by itself and resumes from the last
+// event id; "resync" means events were missed, so reload everything.
+const MAX_ACTIVITY = 50;
+const quiet = new Set(["cycle_finished", "stats_changed"]);
+
+function logActivity(e) {
+  if (quiet.has(e.kind)) return;
+  const li = document.createElement("li");
+  const time = document.createElement("time");
+  time.textContent = new Date(e.time).toLocaleTimeString();
+  li.append(time, e.error ? `${e.message}: ${e.error}` : e.message);
+  if (e.error) li.className = "error";
+  const list = $("activity");
+  list.prepend(li);
+  while (list.children.length > MAX_ACTIVITY) list.lastChild.remove();
+}
+
+const stream = new EventSource("/api/events");
+stream.onopen = () => ($("live").textContent = "live");
+stream.onerror = () => ($("live").textContent = "reconnecting…");
+stream.addEventListener("resync", refresh);
+
+const handlers = {
+  scan_result: (e) => renderScan(e.data),
+  forge_ui: (e) => {
+    if (!e.forge_open || !e.has_ores) $("multiplier").textContent = "–";
+  },
+  macro_started: refresh,
+  macro_stopped: refresh,
+  macro_crashed: refresh,
+  cycle_started: (e) => renderMacro({ state: "running", cycle: e.cycle }),
+  cycle_finished: refresh,
+  stats_changed: refresh,
+  session_report: refresh,
+};
+
+[
+  "macro_started", "macro_stopped", "macro_crashed", "sell_failed",
+  "game_window_lost", "ore_found", "level_up", "money_milestone",
+  "session_report", "scan_result", "forge_ui", "cycle_started",
+  "cycle_finished", "stats_changed", "error",
+].forEach((kind) => {
+  stream.addEventListener(kind, (msg) => {
+    const e = JSON.parse(msg.data);
+    logActivity(e);
+    if (handlers[kind]) handlers[kind](e);
+  });
+});
+
+// Session duration still needs the occasional refresh
+setInterval(refresh, 30000);
@@ -1,20 +1,104 @@
 package main
 
 import (
+	"flag"
 	"forger-companion/internal/app"
 	"forger-companion/internal/config"
+	"forger-companion/internal/data"
+	"forger-companion/internal/events"
+	"forger-companion/internal/macro"
+	"forger-companion/internal/ocr"
+	"forger-companion/internal/service"
+	"forger-companion/internal/webui"
 	"log"
+	"os"
+	"strconv"
 )
 
 func main() {
+	configFile := flag.String("config", os.Getenv("FORGER_CONFIG"), "settings file to use instead of ~/.forger-companion/settings.json")
+	profile := flag.String("profile", os.Getenv("FORGER_PROFILE"), "switch to this settings profile before starting")
+	webPort := flag.Int("web", 0, "serve the web API and phone UI on this port (same as -set web.enabled=true -set web.port=N)")
+	sets := setFlags{}
+	flag.Var(sets, "set", "override a setting for this run, e.g. -set webhook.cycle_interval=3 (repeatable)")
+	flag.Parse()
+
+	if *configFile != "" {
+		config.SetPath(*configFile)
+	}
+	if *webPort != 0 {
+		sets["web.enabled"] = "true"
+		sets["web.port"] = strconv.Itoa(*webPort)
+	}
+	config.SetFlagOverrides(sets)
+
+	// Subcommands
+	switch flag.Arg(0) {
+	case "profile":
+		exitOnError(runProfileCommand(flag.Args()[1:]))
+		return
+	case "config":
+		exitOnError(runConfigCommand(flag.Args()[1:]))
+		return
+	case "template":
+		exitOnError(runTemplateCommand(flag.Args()[1:]))
+		return
+	}
+
 	// Load config
-	cfg, err := config.Load()
-	if err != nil {
-		log.Printf("Failed to load config: %v", err)
-		cfg = config.Default()
+	var cfg *config.Config
+	var err error
+	if *profile != "" {
+		if cfg, err = config.SwitchProfile(*profile); err != nil {
+			log.Printf("Failed to switch to profile %q: %v", *profile, err)
+		}
+	}
+	if cfg == nil {
+		cfg, err = config.Load()
+	}
+	switch {
+	case err != nil && cfg != nil:
+		log.Printf("Invalid settings were reset for this run; nothing is saved until they are fixed: %v", err)
+	case err != nil:
+		log.Printf("Failed to load config, running with defaults; nothing is saved until it is fixed: %v", err)
+		cfg = config.Fallback(err)
+	}
+
+	// Load user ore overrides
+	if err := data.LoadFile(config.OresPath()); err != nil {
+		log.Fatalf("Failed to load ores: %v", err)
+	}
+
+	bus := events.NewBus()
+	scanner := ocr.NewScanner()
+	svc := service.New(cfg, bus, scanner, macro.New(cfg, scanner, bus))
+	svc.Start()
+	defer svc.Close()
+
+	// Commands that need the scanner and macro
+	switch flag.Arg(0) {
+	case "scan":
+		exitOnError(runScanCommand(svc))
+		return
+	case "run":
+		startWebServer(svc)
+		exitOnError(runHeadless(svc, flag.Args()[1:]))
+		return
 	}
 
 	// Create and run app
-	application := app.NewSimple(cfg)
-	application.Run()
+	startWebServer(svc)
+	app.New(svc).Run()
+}
+
+func startWebServer(svc *service.Service) {
+	if !svc.Config.Current().Web.Enabled {
+		return
+	}
+	server := webui.NewServer(svc)
+	go func() {
+		if err := server.Start(); err != nil {
+			log.Printf("[WebUI] %v", err)
+		}
+	}()
 }